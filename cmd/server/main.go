@@ -2,16 +2,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/database"
 	apphandlers "github.com/mariopaath23/backend-jte-ticketing/internal/handlers"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/mail"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/notify"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/password"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/scheduler"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/users"
 )
 
 func main() {
@@ -33,39 +41,171 @@ func main() {
 	}
 	log.Println("SUCCESS: Connection to MongoDB established.")
 
+	mailer, err := mail.New(cfg)
+	if err != nil {
+		log.Fatalf("could not configure mail transport: %v", err)
+	}
+
+	passwordPolicy, err := password.LoadPolicy(cfg)
+	if err != nil {
+		log.Fatalf("could not load password policy: %v", err)
+	}
+
+	tokens, err := auth.NewTokens(cfg)
+	if err != nil {
+		log.Fatalf("could not load JWT keys: %v", err)
+	}
+
+	notifier, err := notify.New(cfg, db, mailer)
+	if err != nil {
+		log.Fatalf("could not configure notifications: %v", err)
+	}
+	sessionStore := sessions.NewStore(db, cfg.RefreshTokenTTL)
+	authn := middleware.NewAuthenticator(tokens, sessionStore, users.NewStore(db))
+
 	// Initialize all handlers
-	userHandler := apphandlers.NewUserHandler(db)
+	accountHandler := apphandlers.NewAccountHandler(db, sessionStore, mailer, passwordPolicy, cfg)
+	userHandler := apphandlers.NewUserHandler(db, sessionStore, tokens, accountHandler, cfg)
 	statusHandler := apphandlers.NewStatusHandler(db)
 	announcementHandler := apphandlers.NewAnnouncementHandler(db)
 	catalogHandler := apphandlers.NewCatalogHandler(db)
 	reservationHandler := apphandlers.NewReservationHandler(db)
+	bookingPolicyHandler := apphandlers.NewBookingPolicyHandler(db)
+	blackoutHandler := apphandlers.NewBlackoutHandler(db)
+	maintenanceHandler := apphandlers.NewMaintenanceHandler(db)
+	roomHandler := apphandlers.NewRoomHandler(db, notifier)
+	notificationHandler := apphandlers.NewNotificationHandler(db)
+	sessionHandler := apphandlers.NewSessionHandler(db, sessionStore, cfg)
+	twoFactorHandler := apphandlers.NewTwoFactorHandler(db, sessionStore, tokens, cfg)
+	profileHandler := apphandlers.NewProfileHandler(db)
+	adminUserHandler := apphandlers.NewAdminUserHandler(db, sessionStore)
+	inventoryHandler := apphandlers.NewInventoryHandler(db)
+	loanHandler := apphandlers.NewLoanHandler(db)
+	assetUnitHandler := apphandlers.NewAssetUnitHandler(db)
+	jwksHandler := apphandlers.NewJWKSHandler(tokens)
 
 	r := mux.NewRouter()
+	r.HandleFunc("/.well-known/jwks.json", jwksHandler.GetJWKS).Methods("GET")
+
 	api := r.PathPrefix("/api").Subrouter()
 
 	// --- Public Routes ---
 	api.HandleFunc("/register", userHandler.Register).Methods("POST")
 	api.HandleFunc("/login", userHandler.Login).Methods("POST")
+	api.HandleFunc("/login/2fa", userHandler.LoginTwoFactor).Methods("POST")
 	api.HandleFunc("/logout", userHandler.Logout).Methods("POST")
+	api.HandleFunc("/token/refresh", userHandler.RefreshToken).Methods("POST")
+	api.HandleFunc("/forgot-password", accountHandler.ForgotPassword).Methods("POST")
+	api.HandleFunc("/reset-password", accountHandler.ResetPassword).Methods("POST")
+	api.HandleFunc("/verify-email", accountHandler.VerifyEmail).Methods("POST")
+	api.HandleFunc("/verify-email/resend", accountHandler.ResendVerification).Methods("POST")
 	api.HandleFunc("/status/rooms", statusHandler.GetRooms).Methods("GET")
 	api.HandleFunc("/status/inventory", statusHandler.GetInventoryRequests).Methods("GET")
-	api.HandleFunc("/announcements", announcementHandler.GetAnnouncements).Methods("GET")
+	api.Handle("/announcements", authn.OptionalAuth(http.HandlerFunc(announcementHandler.GetAnnouncements))).Methods("GET")
 	api.HandleFunc("/catalog/search", catalogHandler.SearchCatalog).Methods("GET")
 	api.HandleFunc("/catalog/room/{id}", catalogHandler.GetRoomByID).Methods("GET")
+	api.HandleFunc("/catalog/item/{id}", catalogHandler.GetItemByID).Methods("GET")
+	api.HandleFunc("/catalog/room/{id}/availability", catalogHandler.GetRoomAvailability).Methods("GET")
+	api.HandleFunc("/booking-policies", bookingPolicyHandler.GetPolicies).Methods("GET")
 
 	// --- Protected Routes ---
-	api.Handle("/reservations", middleware.Auth(http.HandlerFunc(reservationHandler.CreateReservation))).Methods("POST")
-	api.Handle("/validate-token", middleware.Auth(http.HandlerFunc(userHandler.ValidateToken))).Methods("GET")
-	api.Handle("/login-logs", middleware.Auth(http.HandlerFunc(userHandler.GetLoginLogs))).Methods("GET")
+	api.Handle("/reservations", authn.Auth(http.HandlerFunc(reservationHandler.CreateReservation))).Methods("POST")
+	api.Handle("/reservations", authn.Auth(http.HandlerFunc(reservationHandler.ListMyReservations))).Methods("GET")
+	api.Handle("/reservations/series", authn.Auth(http.HandlerFunc(reservationHandler.CreateReservationSeries))).Methods("POST")
+	api.Handle("/reservations/series/{id}", authn.Auth(http.HandlerFunc(reservationHandler.GetReservationSeries))).Methods("GET")
+	api.Handle("/reservations/series/{id}", authn.Auth(http.HandlerFunc(reservationHandler.UpdateReservationSeries))).Methods("PATCH")
+	api.Handle("/reservations/series/{id}/cancel", authn.Auth(http.HandlerFunc(reservationHandler.CancelReservationSeries))).Methods("POST")
+	api.Handle("/reservations/{id}", authn.Auth(http.HandlerFunc(reservationHandler.GetMyReservation))).Methods("GET")
+	api.Handle("/reservations/{id}", authn.Auth(http.HandlerFunc(reservationHandler.UpdateMyReservation))).Methods("PATCH")
+	api.Handle("/reservations/{id}/cancel", authn.Auth(http.HandlerFunc(reservationHandler.CancelMyReservation))).Methods("POST")
+	api.Handle("/inventory/loans", authn.Auth(http.HandlerFunc(loanHandler.CreateLoan))).Methods("POST")
+	api.Handle("/inventory/loans", authn.Auth(http.HandlerFunc(loanHandler.ListMyLoans))).Methods("GET")
+	api.Handle("/validate-token", authn.Auth(http.HandlerFunc(userHandler.ValidateToken))).Methods("GET")
+	api.Handle("/me", authn.Auth(http.HandlerFunc(profileHandler.GetMe))).Methods("GET")
+	api.Handle("/me", authn.Auth(http.HandlerFunc(profileHandler.UpdateMe))).Methods("PATCH")
+	api.Handle("/login-logs", authn.Auth(http.HandlerFunc(userHandler.GetLoginLogs))).Methods("GET")
+	api.Handle("/password/change", authn.Auth(http.HandlerFunc(accountHandler.ChangePassword))).Methods("POST")
+	api.Handle("/2fa/enroll", authn.Auth(http.HandlerFunc(twoFactorHandler.Enroll))).Methods("POST")
+	api.Handle("/2fa/verify", authn.Auth(http.HandlerFunc(twoFactorHandler.ConfirmEnrollment))).Methods("POST")
+	api.Handle("/2fa/disable", authn.Auth(http.HandlerFunc(twoFactorHandler.Disable))).Methods("POST")
+	api.Handle("/2fa/recovery-codes", authn.Auth(http.HandlerFunc(twoFactorHandler.RegenerateRecoveryCodes))).Methods("POST")
+	api.Handle("/sessions", authn.Auth(http.HandlerFunc(sessionHandler.GetMySessions))).Methods("GET")
+	api.Handle("/sessions/revoke-all", authn.Auth(http.HandlerFunc(sessionHandler.RevokeAllMySessions))).Methods("POST")
+	api.Handle("/sessions/{id}", authn.Auth(http.HandlerFunc(sessionHandler.RevokeMySession))).Methods("DELETE")
+	api.Handle("/notifications", authn.Auth(http.HandlerFunc(notificationHandler.GetNotifications))).Methods("GET")
+	api.Handle("/notifications/{id}/read", authn.Auth(http.HandlerFunc(notificationHandler.MarkNotificationRead))).Methods("POST")
+
+	// --- Admin Routes ---
+	// Every admin route requires an authenticated admin (or superadmin), and
+	// each route additionally checks the permission it needs.
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(authn.Auth, middleware.RequireRole(auth.RoleAdmin))
+	admin.Handle("/reservations", middleware.RequirePermission(auth.PermManageReservations)(http.HandlerFunc(reservationHandler.ListReservations))).Methods("GET")
+	admin.Handle("/reservations/{id}/approve", middleware.RequirePermission(auth.PermManageReservations)(http.HandlerFunc(reservationHandler.ApproveReservation))).Methods("POST")
+	admin.Handle("/reservations/{id}/reject", middleware.RequirePermission(auth.PermManageReservations)(http.HandlerFunc(reservationHandler.RejectReservation))).Methods("POST")
+	admin.Handle("/reservations/{id}/revoke", middleware.RequirePermission(auth.PermManageReservations)(http.HandlerFunc(reservationHandler.RevokeReservation))).Methods("POST")
+	admin.Handle("/booking-policies", middleware.RequirePermission(auth.PermManagePolicies)(http.HandlerFunc(bookingPolicyHandler.PutPolicy))).Methods("PUT")
+	admin.Handle("/booking-policies/{id}", middleware.RequirePermission(auth.PermManagePolicies)(http.HandlerFunc(bookingPolicyHandler.DeletePolicy))).Methods("DELETE")
+	admin.Handle("/blackouts", middleware.RequirePermission(auth.PermManageBlackouts)(http.HandlerFunc(blackoutHandler.GetBlackouts))).Methods("GET")
+	admin.Handle("/blackouts", middleware.RequirePermission(auth.PermManageBlackouts)(http.HandlerFunc(blackoutHandler.CreateBlackout))).Methods("POST")
+	admin.Handle("/blackouts/{id}", middleware.RequirePermission(auth.PermManageBlackouts)(http.HandlerFunc(blackoutHandler.UpdateBlackout))).Methods("PUT")
+	admin.Handle("/blackouts/{id}", middleware.RequirePermission(auth.PermManageBlackouts)(http.HandlerFunc(blackoutHandler.DeleteBlackout))).Methods("DELETE")
+	admin.Handle("/rooms", middleware.RequirePermission(auth.PermManageRooms)(http.HandlerFunc(roomHandler.CreateRoom))).Methods("POST")
+	admin.Handle("/rooms/{id}", middleware.RequirePermission(auth.PermManageRooms)(http.HandlerFunc(roomHandler.UpdateRoom))).Methods("PUT")
+	admin.Handle("/rooms/{id}", middleware.RequirePermission(auth.PermManageRooms)(http.HandlerFunc(roomHandler.DeleteRoom))).Methods("DELETE")
+	admin.Handle("/rooms/{id}/maintenance", middleware.RequirePermission(auth.PermManageMaintenance)(http.HandlerFunc(maintenanceHandler.GetRoomMaintenance))).Methods("GET")
+	admin.Handle("/rooms/{id}/maintenance", middleware.RequirePermission(auth.PermManageMaintenance)(http.HandlerFunc(maintenanceHandler.CreateRoomMaintenance))).Methods("POST")
+	admin.Handle("/maintenance/{id}", middleware.RequirePermission(auth.PermManageMaintenance)(http.HandlerFunc(maintenanceHandler.UpdateMaintenance))).Methods("PUT")
+	admin.Handle("/maintenance/{id}", middleware.RequirePermission(auth.PermManageMaintenance)(http.HandlerFunc(maintenanceHandler.DeleteMaintenance))).Methods("DELETE")
+	admin.Handle("/users/{id}/sessions", middleware.RequirePermission(auth.PermManageSessions)(http.HandlerFunc(sessionHandler.GetUserSessions))).Methods("GET")
+	admin.Handle("/inventory/items", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(inventoryHandler.CreateItem))).Methods("POST")
+	admin.Handle("/inventory/items/{id}", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(inventoryHandler.UpdateItem))).Methods("PUT")
+	admin.Handle("/inventory/items/{id}", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(inventoryHandler.DeleteItem))).Methods("DELETE")
+	admin.Handle("/inventory/items/{id}/units", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.CreateUnit))).Methods("POST")
+	admin.Handle("/inventory/items/{id}/units", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.ListItemUnits))).Methods("GET")
+	admin.Handle("/inventory/items/{id}/labels", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.GetItemLabelSheet))).Methods("GET")
+	admin.Handle("/inventory/units/tag/{tag}", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.GetUnitByTag))).Methods("GET")
+	admin.Handle("/inventory/units/{id}", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.GetUnit))).Methods("GET")
+	admin.Handle("/inventory/units/{id}", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.UpdateUnit))).Methods("PATCH")
+	admin.Handle("/inventory/units/{id}/label", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.GetUnitLabel))).Methods("GET")
+	admin.Handle("/inventory/units/{id}/repair", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.StartRepair))).Methods("POST")
+	admin.Handle("/inventory/units/{id}/repair/complete", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.FinishRepair))).Methods("POST")
+	admin.Handle("/inventory/units/{id}/retire", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(assetUnitHandler.RetireUnit))).Methods("POST")
+	admin.Handle("/inventory/loans", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(loanHandler.ListLoans))).Methods("GET")
+	admin.Handle("/inventory/loans/{id}/approve", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(loanHandler.ApproveLoan))).Methods("POST")
+	admin.Handle("/inventory/loans/{id}/reject", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(loanHandler.RejectLoan))).Methods("POST")
+	admin.Handle("/inventory/loans/{id}/pickup", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(loanHandler.PickupLoan))).Methods("POST")
+	admin.Handle("/inventory/loans/{id}/return", middleware.RequirePermission(auth.PermManageInventory)(http.HandlerFunc(loanHandler.ReturnLoan))).Methods("POST")
+	admin.Handle("/users", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(adminUserHandler.ListUsers))).Methods("GET")
+	admin.Handle("/users/{id}", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(adminUserHandler.GetUser))).Methods("GET")
+	admin.Handle("/users/{id}", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(adminUserHandler.DeleteUser))).Methods("DELETE")
+	admin.Handle("/users/{id}/role", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(adminUserHandler.ChangeRole))).Methods("PUT")
+	admin.Handle("/users/{id}/disable", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(adminUserHandler.DisableUser))).Methods("POST")
+	admin.Handle("/users/{id}/enable", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(adminUserHandler.EnableUser))).Methods("POST")
+	admin.Handle("/users/{id}/profile", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(profileHandler.UpdateUserProfile))).Methods("PATCH")
+	admin.Handle("/users/{id}/unlock", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(userHandler.UnlockUser))).Methods("POST")
+	admin.Handle("/users/{id}/2fa/reset", middleware.RequirePermission(auth.PermManageUsers)(http.HandlerFunc(twoFactorHandler.ResetUserTwoFactor))).Methods("POST")
+	admin.Handle("/users/{id}/sessions/revoke", middleware.RequirePermission(auth.PermManageSessions)(http.HandlerFunc(sessionHandler.RevokeUserSessions))).Methods("POST")
 
 	// --- CORS Configuration ---
-	allowedOrigins := handlers.AllowedOrigins([]string{"http://localhost:3000"})
-	allowedMethods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	allowedOrigins := handlers.AllowedOrigins(cfg.CORSAllowedOrigins)
+	allowedMethods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
 	allowedHeaders := handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization", "Credentials"})
 	allowCredentials := handlers.AllowCredentials()
 
-	port := cfg.APIPort
-	fmt.Printf("Server starting on port %s...\n", port)
+	server := &http.Server{
+		Addr:         ":" + cfg.APIPort,
+		Handler:      handlers.CORS(allowedOrigins, allowedMethods, allowedHeaders, allowCredentials)(r),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	if cfg.SchedulerEnabled {
+		go scheduler.New(db, notifier, cfg).Run(context.Background())
+		log.Printf("Scheduler started, running every %s", cfg.SchedulerInterval)
+	}
+
+	fmt.Printf("Server starting on port %s...\n", cfg.APIPort)
 
-	log.Fatal(http.ListenAndServe(":"+port, handlers.CORS(allowedOrigins, allowedMethods, allowedHeaders, allowCredentials)(r)))
+	log.Fatal(server.ListenAndServe())
 }
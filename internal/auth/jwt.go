@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -11,65 +13,109 @@ import (
 // Claims struct will be encoded to a JWT.
 // We add jwt.RegisteredClaims as an embedded type, to provide fields like expiry.
 type Claims struct {
-	UserID primitive.ObjectID `json:"user_id"`
-	Email  string             `json:"email"`
-	Role   string             `json:"role"`
+	UserID    primitive.ObjectID `json:"user_id"`
+	Email     string             `json:"email"`
+	Role      string             `json:"role"`
+	SessionID primitive.ObjectID `json:"sid"`
+	AMR       []string           `json:"amr,omitempty"` // how the user authenticated, e.g. ["pwd", "otp"]
 	jwt.RegisteredClaims
+
+	// twoFactorRequired is set by ValidateJWT when the role must sign in
+	// with a second factor. It is not part of the token.
+	twoFactorRequired bool
+}
+
+// Tokens signs and checks access tokens with the configured keys and records
+// which roles must sign in with a second factor.
+type Tokens struct {
+	keys           *KeySet
+	issuer         string
+	audience       string
+	accessTTL      time.Duration
+	twoFactorRoles map[string]bool
 }
 
-// GenerateJWT creates a new JWT token for a given email.
-func GenerateJWT(userID primitive.ObjectID, email, role string) (string, error) {
-	cfg, err := config.LoadConfig(".")
+// NewTokens loads the signing keys and token settings from cfg.
+func NewTokens(cfg config.Config) (*Tokens, error) {
+	ks, err := LoadKeySet(cfg.JWTSigningKeyFile, cfg.JWTVerificationKeyFiles)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	twoFactorRoles := map[string]bool{}
+	for _, role := range cfg.TwoFactorRequiredRoles {
+		twoFactorRoles[role] = true
+	}
+	return &Tokens{
+		keys:           ks,
+		issuer:         cfg.JWTIssuer,
+		audience:       cfg.JWTAudience,
+		accessTTL:      cfg.AccessTokenTTL,
+		twoFactorRoles: twoFactorRoles,
+	}, nil
+}
 
-	// Set token expiration time
-	expirationTime := time.Now().Add(24 * time.Hour)
+// JWKS returns the public keys tokens are verified with.
+func (t *Tokens) JWKS() JWKSet {
+	return t.keys.JWKS()
+}
 
-	// Create the claims
+// TwoFactorRequired reports whether users with role must use two-factor authentication.
+func (t *Tokens) TwoFactorRequired(role string) bool {
+	return t.twoFactorRoles[role]
+}
+
+// GenerateJWT creates a new short-lived access token for a user's session.
+// amr lists the authentication methods the session was established with.
+func (t *Tokens) GenerateJWT(userID primitive.ObjectID, email, role string, sessionID primitive.ObjectID, amr []string) (string, error) {
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		AMR:       amr,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        primitive.NewObjectID().Hex(),
+			Issuer:    t.issuer,
+			Subject:   userID.Hex(),
+			Audience:  jwt.ClaimStrings{t.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.accessTTL)),
 		},
 	}
 
-	// Create the token using the HS256 algorithm and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign the token with our secret key
-	tokenString, err := token.SignedString([]byte(cfg.JWTSecretKey))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+	return t.keys.Sign(claims)
 }
 
-// ValidateJWT checks if the token is valid and returns the claims.
-func ValidateJWT(tokenString string) (*Claims, error) {
-	cfg, err := config.LoadConfig(".")
-	if err != nil {
-		return nil, err
-	}
-
+// ValidateJWT checks if the token is valid and returns the claims. Only the
+// asymmetric algorithms of our keys are accepted, and the issuer, audience
+// and expiry are required.
+func (t *Tokens) ValidateJWT(tokenString string) (*Claims, error) {
 	claims := &Claims{}
-
-	// Parse the JWT string and store the result in `claims`.
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.JWTSecretKey), nil
-	})
-
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}))
+	token, err := parser.ParseWithClaims(tokenString, claims, t.keys.keyFunc)
 	if err != nil {
 		return nil, err
 	}
-
 	if !token.Valid {
-		return nil, err
+		return nil, errors.New("invalid token")
+	}
+
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now, true) {
+		return nil, errors.New("token has no expiry")
+	}
+	if !claims.VerifyIssuer(t.issuer, true) {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !claims.VerifyAudience(t.audience, true) {
+		return nil, errors.New("token is not meant for this audience")
 	}
 
+	claims.twoFactorRequired = t.TwoFactorRequired(claims.Role)
 	return claims, nil
 }
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestTokens(t *testing.T, signingKeyFile string, verificationKeyFiles ...string) *Tokens {
+	t.Helper()
+	tokens, err := NewTokens(config.Config{
+		JWTSigningKeyFile:       signingKeyFile,
+		JWTVerificationKeyFiles: verificationKeyFiles,
+		JWTIssuer:               "jte-ticketing",
+		JWTAudience:             "jte-ticketing",
+		AccessTokenTTL:          15 * time.Minute,
+		TwoFactorRequiredRoles:  []string{RoleSuperAdmin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+// validClaims returns claims that pass validation.
+func validClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		UserID: primitive.NewObjectID(),
+		Role:   RoleSuperAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "jte-ticketing",
+			Audience:  jwt.ClaimStrings{"jte-ticketing"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims *Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestValidateJWTAcceptsOwnTokens(t *testing.T) {
+	tokens := newTestTokens(t, writeKey(t, newEd25519Key(t)))
+	userID := primitive.NewObjectID()
+	signed, err := tokens.GenerateJWT(userID, "admin@unsrat.ac.id", RoleSuperAdmin, primitive.NewObjectID(), []string{"pwd", "otp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := tokens.ValidateJWT(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != userID || !claims.twoFactorRequired {
+		t.Errorf("got user %s and twoFactorRequired %v, want %s and true", claims.UserID.Hex(), claims.twoFactorRequired, userID.Hex())
+	}
+}
+
+func TestValidateJWTRejects(t *testing.T) {
+	signingKey := newEd25519Key(t)
+	retiredKey := newEd25519Key(t)
+	tokens := newTestTokens(t, writeKey(t, signingKey))
+	kid := tokens.keys.signerKID
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired := newTestTokens(t, writeKey(t, retiredKey))
+
+	withClaims := func(edit func(*Claims)) *Claims {
+		claims := validClaims()
+		edit(claims)
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS256 with the key ID as secret", sign(t, jwt.SigningMethodHS256, []byte(kid), kid, validClaims())},
+		{"alg none", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, kid, validClaims())},
+		{"RS256 under an Ed25519 key ID", sign(t, jwt.SigningMethodRS256, rsaKey, kid, validClaims())},
+		{"no key ID", sign(t, jwt.SigningMethodEdDSA, signingKey, "", validClaims())},
+		{"unknown key ID", sign(t, jwt.SigningMethodEdDSA, signingKey, "unknown", validClaims())},
+		{"rotated-out key", sign(t, jwt.SigningMethodEdDSA, retiredKey, retired.keys.signerKID, validClaims())},
+		{"wrong issuer", sign(t, jwt.SigningMethodEdDSA, signingKey, kid, withClaims(func(c *Claims) { c.Issuer = "someone-else" }))},
+		{"no issuer", sign(t, jwt.SigningMethodEdDSA, signingKey, kid, withClaims(func(c *Claims) { c.Issuer = "" }))},
+		{"wrong audience", sign(t, jwt.SigningMethodEdDSA, signingKey, kid, withClaims(func(c *Claims) { c.Audience = jwt.ClaimStrings{"someone-else"} }))},
+		{"no audience", sign(t, jwt.SigningMethodEdDSA, signingKey, kid, withClaims(func(c *Claims) { c.Audience = nil }))},
+		{"expired", sign(t, jwt.SigningMethodEdDSA, signingKey, kid, withClaims(func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }))},
+		{"no expiry", sign(t, jwt.SigningMethodEdDSA, signingKey, kid, withClaims(func(c *Claims) { c.ExpiresAt = nil }))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tokens.ValidateJWT(tt.token); err == nil {
+				t.Error("token was accepted")
+			}
+		})
+	}
+}
+
+func TestValidateJWTAcceptsRetiredKey(t *testing.T) {
+	retiredFile := writeKey(t, newEd25519Key(t))
+	old := newTestTokens(t, retiredFile)
+	signed, err := old.GenerateJWT(primitive.NewObjectID(), "student@unsrat.ac.id", RoleStudent, primitive.NewObjectID(), []string{"pwd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestTokens(t, writeKey(t, newEd25519Key(t)), retiredFile)
+	if _, err := rotated.ValidateJWT(signed); err != nil {
+		t.Errorf("token signed with the retired key: %v", err)
+	}
+
+	// New tokens are signed with the new key only.
+	fresh, err := rotated.GenerateJWT(primitive.NewObjectID(), "student@unsrat.ac.id", RoleStudent, primitive.NewObjectID(), []string{"pwd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.ValidateJWT(fresh); err == nil {
+		t.Error("token signed with the new key was accepted by the old key set")
+	}
+}
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// minRSABits is the smallest RSA key accepted for signing or verification.
+const minRSABits = 2048
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// verificationKey is a public key tokens may be signed with.
+type verificationKey struct {
+	key    crypto.PublicKey
+	method jwt.SigningMethod
+	jwk    JWK
+}
+
+// KeySet holds the key new tokens are signed with and every key still
+// accepted for verification. During a rotation the previous key stays in
+// the set until tokens signed with it have expired.
+type KeySet struct {
+	signer    crypto.Signer
+	signerKID string
+	method    jwt.SigningMethod
+	verifiers map[string]verificationKey
+	order     []string
+}
+
+// LoadKeySet reads the PEM signing key and any extra PEM verification keys.
+// With no signing key file an ephemeral Ed25519 key is generated, which is
+// only suitable for development: tokens stop validating on restart.
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	var signer crypto.Signer
+	if signingKeyFile == "" {
+		log.Println("WARNING: JWT_SIGNING_KEY_FILE is not set; using an ephemeral Ed25519 key. Tokens will not survive a restart.")
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	} else {
+		key, err := readPrivateKey(signingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	}
+
+	ks := &KeySet{signer: signer, verifiers: map[string]verificationKey{}}
+	kid, err := ks.add(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("auth: signing key %s: %w", signingKeyFile, err)
+	}
+	ks.signerKID = kid
+	ks.method = ks.verifiers[kid].method
+
+	for _, file := range verificationKeyFiles {
+		pub, err := readPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := ks.add(pub); err != nil {
+			return nil, fmt.Errorf("auth: verification key %s: %w", file, err)
+		}
+	}
+	return ks, nil
+}
+
+// add registers a public key for verification and returns its key ID.
+func (ks *KeySet) add(pub crypto.PublicKey) (string, error) {
+	var vk verificationKey
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSABits {
+			return "", fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		vk = verificationKey{key: key, method: jwt.SigningMethodRS256, jwk: JWK{
+			Kty: "RSA",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			N:   b64(key.N.Bytes()),
+			E:   b64(big.NewInt(int64(key.E)).Bytes()),
+		}}
+	case ed25519.PublicKey:
+		vk = verificationKey{key: key, method: jwt.SigningMethodEdDSA, jwk: JWK{
+			Kty: "OKP",
+			Alg: jwt.SigningMethodEdDSA.Alg(),
+			Crv: "Ed25519",
+			X:   b64(key),
+		}}
+	default:
+		return "", errors.New("unsupported key type, use RSA or Ed25519")
+	}
+
+	kid, err := thumbprint(vk.jwk)
+	if err != nil {
+		return "", err
+	}
+	vk.jwk.Kid = kid
+	vk.jwk.Use = "sig"
+	if _, exists := ks.verifiers[kid]; !exists {
+		ks.order = append(ks.order, kid)
+	}
+	ks.verifiers[kid] = vk
+	return kid, nil
+}
+
+// Sign signs the claims with the current signing key and sets the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.method, claims)
+	token.Header["kid"] = ks.signerKID
+	return token.SignedString(ks.signer)
+}
+
+// keyFunc picks the verification key named by the token's kid and checks
+// that the token's algorithm matches that key.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	vk, ok := ks.verifiers[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != vk.method.Alg() {
+		return nil, fmt.Errorf("algorithm %s does not match key %s", token.Method.Alg(), kid)
+	}
+	return vk.key, nil
+}
+
+// JWKS returns the public verification keys, the signing key first.
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{ks.verifiers[ks.signerKID].jwk}}
+	for _, kid := range ks.order {
+		if kid != ks.signerKID {
+			set.Keys = append(set.Keys, ks.verifiers[kid].jwk)
+		}
+	}
+	return set
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, used as the key ID so it
+// is stable across restarts and servers.
+func thumbprint(jwk JWK) (string, error) {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return b64(sum[:]), nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// readPrivateKey reads a PKCS#8 or PKCS#1 PEM private key.
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %s: %w", path, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("auth: %s: unsupported private key", path)
+		}
+		return signer, nil
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %s: %w", path, err)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("auth: %s: expected a private key, found %q", path, block.Type)
+}
+
+// readPublicKey reads a PEM public key. A private key file is accepted too,
+// so a retired signing key can be kept as is for verification.
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %s: %w", path, err)
+		}
+		return key, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %s: %w", path, err)
+		}
+		return key, nil
+	case "PRIVATE KEY", "RSA PRIVATE KEY":
+		signer, err := readPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+	return nil, fmt.Errorf("auth: %s: expected a public key, found %q", path, block.Type)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: reading key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("auth: %s: no PEM data found", path)
+	}
+	return block, nil
+}
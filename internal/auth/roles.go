@@ -0,0 +1,106 @@
+package auth
+
+// Roles, from least to most privileged. Each role includes every permission of
+// the roles below it: superadmin ⊇ admin ⊇ student.
+const (
+	RoleStudent    = "student"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "superadmin"
+)
+
+var roleRank = map[string]int{
+	RoleStudent:    1,
+	RoleAdmin:      2,
+	RoleSuperAdmin: 3,
+}
+
+// Permissions checked by middleware.RequirePermission and by handlers that
+// behave differently for privileged users.
+const (
+	PermViewPrivateAnnouncements = "announcements:view_private"
+	PermViewAllReservations      = "reservations:view_all"
+	PermManageReservations       = "reservations:manage"
+	PermManageRooms              = "rooms:manage"
+	PermManageMaintenance        = "maintenance:manage"
+	PermManageBlackouts          = "blackouts:manage"
+	PermManagePolicies           = "policies:manage"
+	PermManageSessions           = "sessions:manage"
+	PermManageUsers              = "users:manage"
+	PermManageInventory          = "inventory:manage"
+)
+
+// permissionRoles maps each permission to the least privileged role granted it.
+var permissionRoles = map[string]string{
+	PermViewPrivateAnnouncements: RoleAdmin,
+	PermViewAllReservations:      RoleAdmin,
+	PermManageReservations:       RoleAdmin,
+	PermManageRooms:              RoleAdmin,
+	PermManageMaintenance:        RoleAdmin,
+	PermManageBlackouts:          RoleAdmin,
+	PermManagePolicies:           RoleAdmin,
+	PermManageSessions:           RoleAdmin,
+	PermManageUsers:              RoleAdmin,
+	PermManageInventory:          RoleAdmin,
+}
+
+// Authentication methods recorded in the amr claim.
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+)
+
+// HasAMR reports whether the token was issued after the given authentication method.
+func (c *Claims) HasAMR(method string) bool {
+	for _, m := range c.AMR {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// TwoFactorSatisfied reports whether the token meets the two-factor
+// requirement of its role.
+func (c *Claims) TwoFactorSatisfied() bool {
+	return !c.twoFactorRequired || c.HasAMR(AMROTP)
+}
+
+// Can reports whether the token's user has the permission, taking the
+// two-factor requirement of the role into account. Handlers should prefer
+// it over the role-only Can.
+func (c *Claims) Can(permission string) bool {
+	return Can(c.Role, permission) && c.TwoFactorSatisfied()
+}
+
+// HasRole reports whether role is at least as privileged as required.
+// Unknown roles have no privileges.
+func HasRole(role, required string) bool {
+	rank, known := roleRank[role]
+	return known && rank >= roleRank[required]
+}
+
+// IsRole reports whether role is one of the known roles.
+func IsRole(role string) bool {
+	_, known := roleRank[role]
+	return known
+}
+
+// CanAssignRole reports whether a user with role actor may move an account
+// from role current to role target. Only a superadmin may grant a privileged
+// role or change the role of a privileged account.
+func CanAssignRole(actor, current, target string) bool {
+	if !IsRole(target) || !HasRole(actor, RoleAdmin) {
+		return false
+	}
+	if HasRole(current, RoleAdmin) || HasRole(target, RoleAdmin) {
+		return HasRole(actor, RoleSuperAdmin)
+	}
+	return true
+}
+
+// Can reports whether role has been granted the permission. Unknown
+// permissions are denied.
+func Can(role, permission string) bool {
+	required, known := permissionRoles[permission]
+	return known && HasRole(role, required)
+}
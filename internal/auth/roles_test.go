@@ -0,0 +1,108 @@
+package auth
+
+import "testing"
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		role, required string
+		want           bool
+	}{
+		{RoleStudent, RoleStudent, true},
+		{RoleStudent, RoleAdmin, false},
+		{RoleAdmin, RoleStudent, true},
+		{RoleAdmin, RoleAdmin, true},
+		{RoleAdmin, RoleSuperAdmin, false},
+		{RoleSuperAdmin, RoleAdmin, true},
+		{RoleSuperAdmin, RoleSuperAdmin, true},
+		{"", RoleStudent, false},
+		{"root", RoleStudent, false},
+		{"Admin", RoleStudent, false},
+	}
+	for _, tt := range tests {
+		if got := HasRole(tt.role, tt.required); got != tt.want {
+			t.Errorf("HasRole(%q, %q): got %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestIsRole(t *testing.T) {
+	for _, role := range []string{RoleStudent, RoleAdmin, RoleSuperAdmin} {
+		if !IsRole(role) {
+			t.Errorf("IsRole(%q): got false, want true", role)
+		}
+	}
+	for _, role := range []string{"", "root", "SUPERADMIN"} {
+		if IsRole(role) {
+			t.Errorf("IsRole(%q): got true, want false", role)
+		}
+	}
+}
+
+func TestCanAssignRole(t *testing.T) {
+	tests := []struct {
+		actor, current, target string
+		want                   bool
+	}{
+		{RoleStudent, RoleStudent, RoleStudent, false},
+		{RoleAdmin, RoleStudent, RoleStudent, true},
+		{RoleAdmin, RoleStudent, RoleAdmin, false},
+		{RoleAdmin, RoleAdmin, RoleStudent, false},
+		{RoleAdmin, RoleSuperAdmin, RoleStudent, false},
+		{RoleSuperAdmin, RoleStudent, RoleAdmin, true},
+		{RoleSuperAdmin, RoleAdmin, RoleStudent, true},
+		{RoleSuperAdmin, RoleAdmin, RoleSuperAdmin, true},
+		{RoleSuperAdmin, RoleStudent, "root", false},
+		{"root", RoleStudent, RoleStudent, false},
+	}
+	for _, tt := range tests {
+		if got := CanAssignRole(tt.actor, tt.current, tt.target); got != tt.want {
+			t.Errorf("CanAssignRole(%q, %q, %q): got %v, want %v", tt.actor, tt.current, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestPermissionsAreGrantedToAdmins(t *testing.T) {
+	if len(permissionRoles) == 0 {
+		t.Fatal("no permissions defined")
+	}
+	for permission, required := range permissionRoles {
+		if !IsRole(required) {
+			t.Errorf("%s: granted to unknown role %q", permission, required)
+		}
+		if Can(RoleStudent, permission) {
+			t.Errorf("%s: granted to students", permission)
+		}
+		for _, role := range []string{RoleAdmin, RoleSuperAdmin} {
+			if !Can(role, permission) {
+				t.Errorf("%s: not granted to %s", permission, role)
+			}
+		}
+		if Can("", permission) || Can("root", permission) {
+			t.Errorf("%s: granted to an unknown role", permission)
+		}
+	}
+	if Can(RoleSuperAdmin, "unknown:permission") {
+		t.Error("unknown permission granted to superadmin")
+	}
+}
+
+func TestClaimsCan(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims Claims
+		want   bool
+	}{
+		{"admin without two-factor requirement", Claims{Role: RoleAdmin, AMR: []string{AMRPassword}}, true},
+		{"admin missing required second factor", Claims{Role: RoleAdmin, AMR: []string{AMRPassword}, twoFactorRequired: true}, false},
+		{"admin with required second factor", Claims{Role: RoleAdmin, AMR: []string{AMRPassword, AMROTP}, twoFactorRequired: true}, true},
+		{"student with second factor", Claims{Role: RoleStudent, AMR: []string{AMRPassword, AMROTP}}, false},
+		{"unknown role", Claims{Role: "root", AMR: []string{AMRPassword, AMROTP}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.claims.Can(PermManageUsers); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,33 +1,460 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/joho/godotenv"
 )
 
-// Config struct holds all the configuration for the application
-// The values are read by godotenv from a .env file
+// Config struct holds all the configuration for the application.
+// The values are read from environment variables, with an optional .env
+// file filling in anything the environment does not set.
 type Config struct {
+	// AppEnv is "development" or "production". Production refuses to start
+	// without a signing key file or with insecure cookies.
+	AppEnv        string
 	MongoURI      string
 	MongoDatabase string
-	JWTSecretKey  string
 	APIPort       string
+
+	// HTTP server timeouts.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// CORSAllowedOrigins lists the frontend origins allowed to call the API
+	// with credentials.
+	CORSAllowedOrigins []string
+
+	// TrustedProxies lists the reverse proxies, as CIDRs or single
+	// addresses, whose X-Forwarded-For header is believed. Requests from any
+	// other address are attributed to that address.
+	TrustedProxies []*net.IPNet
+
+	// Access tokens are signed with the PEM private key in JWTSigningKeyFile
+	// (RSA or Ed25519). JWTVerificationKeyFiles lists retired keys that are
+	// still accepted during a rotation.
+	JWTSigningKeyFile       string
+	JWTVerificationKeyFiles []string
+	JWTIssuer               string
+	JWTAudience             string
+
+	// Token lifetimes. Access tokens are short-lived; clients renew them with
+	// the refresh token, which lives as long as the session.
+	AccessTokenTTL            time.Duration
+	RefreshTokenTTL           time.Duration
+	PasswordResetTokenTTL     time.Duration
+	EmailVerificationTokenTTL time.Duration
+	LoginChallengeTTL         time.Duration
+
+	// Attributes of the authentication cookies. CookieSecure must be on
+	// whenever the API is served over HTTPS.
+	CookieSecure   bool
+	CookieSameSite http.SameSite
+	CookieDomain   string
+
+	// AppBaseURL is the frontend address used to build links in emails.
+	AppBaseURL string
+
+	// AllowedEmailDomains lists the institutional domains accepted at
+	// registration, e.g. student.unsrat.ac.id.
+	AllowedEmailDomains []string
+
+	// MailTransport selects how emails are sent: "smtp", "file" (writes
+	// messages to MailDir) or "memory".
+	MailTransport string
+	MailFrom      string
+	MailDir       string
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+	// MailTimeout bounds sending a single email.
+	MailTimeout time.Duration
+
+	// Password policy for new passwords. PasswordBlocklistFile optionally
+	// names a file of common or breached passwords, one per line, rejected on
+	// top of the built-in list. BcryptCost is the cost
+	// for new hashes; older hashes are upgraded at login.
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	PasswordBlocklistFile string
+	BcryptCost            int
+
+	// TOTPIssuer is the name authenticator apps show for the account.
+	// TwoFactorRequiredRoles must sign in with TOTP before using privileged
+	// permissions.
+	TOTPIssuer             string
+	TwoFactorRequiredRoles []string
+
+	// NotificationChannels lists how notifications are delivered: "inapp"
+	// and/or "mail".
+	NotificationChannels []string
+
+	// The scheduler marks overdue loans and sends reminders every
+	// SchedulerInterval. ReservationReminderLead is how long before its start
+	// an approved reservation is reminded of. Disable the scheduler on all but
+	// one instance if several servers share a database; running it on more is
+	// safe but wasteful.
+	SchedulerEnabled        bool
+	SchedulerInterval       time.Duration
+	ReservationReminderLead time.Duration
 }
 
-// LoadConfig reads configuration from file or environment variables.
+// LoadConfig reads configuration from environment variables. A .env file in
+// path is optional; its values are used for variables the environment does
+// not set. A variable set to an empty value clears the default of a text
+// setting. The result is validated, so callers can rely on every field.
 func LoadConfig(path string) (config Config, err error) {
-	// This will load the .env file at the given path
-	// and set the environment variables
-	vars, err := godotenv.Read(path + "/.env")
-	if err != nil {
+	file, err := godotenv.Read(filepath.Join(path, ".env"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return Config{}, err
 	}
+	vars := source(file)
 
 	config = Config{
-		MongoURI:      vars["MONGO_URI"],
-		MongoDatabase: vars["MONGO_DATABASE"],
-		JWTSecretKey:  vars["JWT_SECRET_KEY"],
-		APIPort:       vars["API_PORT"],
+		AppEnv:                  strings.ToLower(vars.stringOr("APP_ENV", "development")),
+		MongoURI:                vars.get("MONGO_URI"),
+		MongoDatabase:           vars.get("MONGO_DATABASE"),
+		APIPort:                 vars.stringOr("API_PORT", "8080"),
+		CORSAllowedOrigins:      splitValues(vars.stringOr("CORS_ALLOWED_ORIGINS", "http://localhost:3000")),
+		JWTSigningKeyFile:       vars.get("JWT_SIGNING_KEY_FILE"),
+		JWTVerificationKeyFiles: splitValues(vars.get("JWT_VERIFICATION_KEY_FILES")),
+		JWTIssuer:               vars.stringOr("JWT_ISSUER", "jte-ticketing"),
+		JWTAudience:             vars.stringOr("JWT_AUDIENCE", "jte-ticketing"),
+		CookieDomain:            vars.get("COOKIE_DOMAIN"),
+		AppBaseURL:              vars.stringOr("APP_BASE_URL", "http://localhost:3000"),
+		AllowedEmailDomains:     splitList(vars.stringOr("ALLOWED_EMAIL_DOMAINS", "student.unsrat.ac.id,unsrat.ac.id")),
+		MailTransport:           vars.stringOr("MAIL_TRANSPORT", "file"),
+		MailFrom:                vars.stringOr("MAIL_FROM", "no-reply@jte.unsrat.ac.id"),
+		MailDir:                 vars.stringOr("MAIL_DIR", "mail"),
+		SMTPHost:                vars.get("SMTP_HOST"),
+		SMTPPort:                vars.stringOr("SMTP_PORT", "587"),
+		SMTPUsername:            vars.get("SMTP_USERNAME"),
+		SMTPPassword:            vars.get("SMTP_PASSWORD"),
+
+		PasswordBlocklistFile: vars.get("PASSWORD_BLOCKLIST_FILE"),
+
+		TOTPIssuer:             vars.stringOr("TOTP_ISSUER", "JTE Ticketing"),
+		TwoFactorRequiredRoles: splitList(vars.get("TWO_FACTOR_REQUIRED_ROLES")),
+
+		NotificationChannels: splitList(vars.stringOr("NOTIFICATION_CHANNELS", "inapp")),
 	}
 
+	durations := []struct {
+		target   *time.Duration
+		key      string
+		fallback time.Duration
+	}{
+		{&config.ReadTimeout, "HTTP_READ_TIMEOUT", 15 * time.Second},
+		{&config.WriteTimeout, "HTTP_WRITE_TIMEOUT", 30 * time.Second},
+		{&config.IdleTimeout, "HTTP_IDLE_TIMEOUT", 2 * time.Minute},
+		{&config.AccessTokenTTL, "ACCESS_TOKEN_TTL", 15 * time.Minute},
+		{&config.RefreshTokenTTL, "REFRESH_TOKEN_TTL", 7 * 24 * time.Hour},
+		{&config.PasswordResetTokenTTL, "PASSWORD_RESET_TOKEN_TTL", time.Hour},
+		{&config.EmailVerificationTokenTTL, "EMAIL_VERIFICATION_TOKEN_TTL", 48 * time.Hour},
+		{&config.LoginChallengeTTL, "LOGIN_CHALLENGE_TTL", 5 * time.Minute},
+		{&config.MailTimeout, "MAIL_TIMEOUT", 30 * time.Second},
+		{&config.SchedulerInterval, "SCHEDULER_INTERVAL", time.Minute},
+		{&config.ReservationReminderLead, "RESERVATION_REMINDER_LEAD", time.Hour},
+	}
+	for _, d := range durations {
+		if *d.target, err = durationOr(vars, d.key, d.fallback); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if config.CookieSecure, err = boolOr(vars, "COOKIE_SECURE", config.AppEnv == "production"); err != nil {
+		return Config{}, err
+	}
+	if config.SchedulerEnabled, err = boolOr(vars, "SCHEDULER_ENABLED", true); err != nil {
+		return Config{}, err
+	}
+	if config.TrustedProxies, err = parseNetworks(vars.get("TRUSTED_PROXIES")); err != nil {
+		return Config{}, err
+	}
+	if config.CookieSameSite, err = sameSite(vars.stringOr("COOKIE_SAMESITE", "lax")); err != nil {
+		return Config{}, err
+	}
+	if config.PasswordMinLength, err = intOr(vars, "PASSWORD_MIN_LENGTH", 8); err != nil {
+		return Config{}, err
+	}
+	if config.PasswordRequireUpper, err = boolOr(vars, "PASSWORD_REQUIRE_UPPER", false); err != nil {
+		return Config{}, err
+	}
+	if config.PasswordRequireLower, err = boolOr(vars, "PASSWORD_REQUIRE_LOWER", true); err != nil {
+		return Config{}, err
+	}
+	if config.PasswordRequireDigit, err = boolOr(vars, "PASSWORD_REQUIRE_DIGIT", true); err != nil {
+		return Config{}, err
+	}
+	if config.PasswordRequireSymbol, err = boolOr(vars, "PASSWORD_REQUIRE_SYMBOL", false); err != nil {
+		return Config{}, err
+	}
+	if config.BcryptCost, err = intOr(vars, "BCRYPT_COST", 12); err != nil {
+		return Config{}, err
+	}
+
+	if err = config.validate(); err != nil {
+		return Config{}, err
+	}
 	return
 }
+
+// validate reports every setting that is missing or out of range.
+func (c Config) validate() error {
+	var problems []string
+
+	if c.AppEnv != "development" && c.AppEnv != "production" {
+		problems = append(problems, "APP_ENV must be development or production")
+	}
+	if c.MongoURI == "" {
+		problems = append(problems, "MONGO_URI is required")
+	}
+	if c.MongoDatabase == "" {
+		problems = append(problems, "MONGO_DATABASE is required")
+	}
+	if port, err := strconv.Atoi(c.APIPort); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, "API_PORT must be a port number between 1 and 65535")
+	}
+
+	// Tokens are signed with a key pair, so there is no shared secret whose
+	// length to check; the key files themselves are checked by the auth
+	// package when they are loaded.
+	if c.JWTSigningKeyFile == "" && c.AppEnv == "production" {
+		problems = append(problems, "JWT_SIGNING_KEY_FILE is required in production")
+	}
+	for _, file := range append([]string{c.JWTSigningKeyFile}, c.JWTVerificationKeyFiles...) {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			problems = append(problems, fmt.Sprintf("JWT key file %s is not readable", file))
+		}
+	}
+	if c.JWTIssuer == "" || c.JWTAudience == "" {
+		problems = append(problems, "JWT_ISSUER and JWT_AUDIENCE must not be empty")
+	}
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.IdleTimeout},
+		{"ACCESS_TOKEN_TTL", c.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", c.RefreshTokenTTL},
+		{"PASSWORD_RESET_TOKEN_TTL", c.PasswordResetTokenTTL},
+		{"EMAIL_VERIFICATION_TOKEN_TTL", c.EmailVerificationTokenTTL},
+		{"LOGIN_CHALLENGE_TTL", c.LoginChallengeTTL},
+		{"MAIL_TIMEOUT", c.MailTimeout},
+		{"SCHEDULER_INTERVAL", c.SchedulerInterval},
+		{"RESERVATION_REMINDER_LEAD", c.ReservationReminderLead},
+	} {
+		if d.value <= 0 {
+			problems = append(problems, d.key+" must be positive")
+		}
+	}
+	if c.AccessTokenTTL >= c.RefreshTokenTTL {
+		problems = append(problems, "ACCESS_TOKEN_TTL must be shorter than REFRESH_TOKEN_TTL")
+	}
+
+	if c.CookieSameSite == http.SameSiteNoneMode && !c.CookieSecure {
+		problems = append(problems, "COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+	}
+	if c.AppEnv == "production" && !c.CookieSecure {
+		problems = append(problems, "COOKIE_SECURE must be true in production")
+	}
+
+	if len(c.CORSAllowedOrigins) == 0 {
+		problems = append(problems, "CORS_ALLOWED_ORIGINS must list at least one origin")
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if !isOrigin(origin) {
+			problems = append(problems, fmt.Sprintf("CORS_ALLOWED_ORIGINS: %q is not an origin like https://example.com", origin))
+		}
+	}
+	if u, err := url.Parse(c.AppBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, "APP_BASE_URL must be an http or https URL")
+	}
+
+	if len(c.NotificationChannels) == 0 {
+		problems = append(problems, "NOTIFICATION_CHANNELS must list at least one channel")
+	}
+	for _, channel := range c.NotificationChannels {
+		if channel != "inapp" && channel != "mail" {
+			problems = append(problems, fmt.Sprintf("NOTIFICATION_CHANNELS: %q is not inapp or mail", channel))
+		}
+	}
+
+	if len(c.AllowedEmailDomains) == 0 {
+		problems = append(problems, "ALLOWED_EMAIL_DOMAINS must list at least one domain")
+	}
+	// The roles are spelled out because the auth package depends on config.
+	for _, role := range c.TwoFactorRequiredRoles {
+		if role != "student" && role != "admin" && role != "superadmin" {
+			problems = append(problems, fmt.Sprintf("TWO_FACTOR_REQUIRED_ROLES: %q is not student, admin or superadmin", role))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// isOrigin reports whether value is a bare scheme://host[:port] origin.
+// Wildcards are refused because the API allows credentials.
+func isOrigin(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		(u.Path == "" || u.Path == "/") && u.RawQuery == "" && !strings.Contains(u.Host, "*")
+}
+
+// sameSite parses a SameSite cookie mode: lax, strict or none.
+func sameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("COOKIE_SAMESITE: %q is not lax, strict or none", value)
+}
+
+// parseNetworks parses a comma-separated list of CIDRs. A plain address
+// stands for itself.
+func parseNetworks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, item := range splitValues(value) {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: %q is not an address or CIDR", item)
+			}
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %q is not an address or CIDR", item)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// durationOr parses the value of key as a duration such as 15m or 168h,
+// returning fallback when it is unset or empty.
+func durationOr(vars source, key string, fallback time.Duration) (time.Duration, error) {
+	value := vars.get(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
+
+// splitValues splits a comma-separated list, keeping the case of each entry.
+func splitValues(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// intOr parses the value of key as an integer, returning fallback when it is
+// unset or empty.
+func intOr(vars source, key string, fallback int) (int, error) {
+	value := vars.get(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return n, nil
+}
+
+// boolOr parses the value of key as a boolean, returning fallback when it is
+// unset or empty.
+func boolOr(vars source, key string, fallback bool) (bool, error) {
+	value := vars.get(key)
+	if value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", key, err)
+	}
+	return b, nil
+}
+
+// source looks settings up in the environment first and then in the .env
+// file. A variable that is set but empty counts as set.
+type source map[string]string
+
+// lookup returns the value of key and whether it is set at all.
+func (s source) lookup(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	value, ok := s[key]
+	return value, ok
+}
+
+// get returns the value of key, or "" when it is unset.
+func (s source) get(key string) string {
+	value, _ := s.lookup(key)
+	return value
+}
+
+// stringOr returns the value of key, or fallback when it is unset. An empty
+// value is kept, so a default can be cleared.
+func (s source) stringOr(key, fallback string) string {
+	if value, ok := s.lookup(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
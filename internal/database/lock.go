@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LocksCollection is the collection holding lock documents, keyed by lock name.
+const LocksCollection = "locks"
+
+// ErrLockTimeout is returned when a lock could not be acquired before the context expired.
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// Lock is a lease-based mutex backed by a single MongoDB document. It works on
+// standalone servers, where multi-document transactions are not available.
+type Lock struct {
+	collection *mongo.Collection
+	key        string
+	owner      primitive.ObjectID
+}
+
+// AcquireLock blocks until the named lock is held or ctx is done. The lease
+// expires after ttl so a crashed holder cannot block others forever; ttl must
+// therefore be longer than the critical section it protects.
+func AcquireLock(ctx context.Context, db *mongo.Database, key string, ttl time.Duration) (*Lock, error) {
+	lock := &Lock{
+		collection: db.Collection(LocksCollection),
+		key:        key,
+		owner:      primitive.NewObjectID(),
+	}
+
+	backoff := 5 * time.Millisecond
+	for {
+		now := time.Now()
+		// The upsert only matches an expired lease. If the lock is currently
+		// held, the upsert tries to insert a second document with the same
+		// _id and fails with a duplicate key error.
+		filter := bson.M{"_id": key, "expires_at": bson.M{"$lte": now}}
+		update := bson.M{"$set": bson.M{"owner": lock.owner, "expires_at": now.Add(ttl)}}
+
+		_, err := lock.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			return lock, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			if ctx.Err() != nil {
+				return nil, ErrLockTimeout
+			}
+			return nil, err
+		}
+
+		// Add jitter so waiting requests do not retry in lockstep.
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-ctx.Done():
+			return nil, ErrLockTimeout
+		case <-time.After(wait):
+		}
+		if backoff < 200*time.Millisecond {
+			backoff *= 2
+		}
+	}
+}
+
+// Release frees the lock if it is still held by this owner.
+func (l *Lock) Release(ctx context.Context) error {
+	_, err := l.collection.DeleteOne(ctx, bson.M{"_id": l.key, "owner": l.owner})
+	return err
+}
@@ -0,0 +1,10 @@
+// Package department holds facts about the department shared by the API and
+// the background jobs.
+package department
+
+import "time"
+
+// Location is the department's local time zone (WITA, UTC+8). Calendar days
+// and opening hours are taken in it, and dates are shown in it. A fixed zone
+// is used so the server does not depend on the host's tzdata.
+var Location = time.FixedZone("WITA", 8*60*60)
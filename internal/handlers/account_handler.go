@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/mail"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/password"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/throttle"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/usertokens"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountHandler handles passwords, email verification and account recovery
+// through mailed links.
+type AccountHandler struct {
+	db             *mongo.Database
+	sessions       *sessions.Store
+	tokens         *usertokens.Store
+	mailer         mail.Mailer
+	passwords      *password.Policy
+	throttle       *throttle.Store
+	proxies        trustedProxies
+	appBaseURL     string
+	allowedDomains []string
+	mailTimeout    time.Duration
+
+	resetTTL        time.Duration
+	verificationTTL time.Duration
+}
+
+// NewAccountHandler creates a new AccountHandler. Links in emails point to
+// cfg.AppBaseURL, and only addresses in cfg.AllowedEmailDomains may register.
+func NewAccountHandler(db *mongo.Database, sessionStore *sessions.Store, mailer mail.Mailer, passwords *password.Policy, cfg config.Config) *AccountHandler {
+	return &AccountHandler{
+		db:              db,
+		sessions:        sessionStore,
+		tokens:          usertokens.NewStore(db),
+		mailer:          mailer,
+		passwords:       passwords,
+		throttle:        throttle.NewStore(db),
+		proxies:         trustedProxies(cfg.TrustedProxies),
+		appBaseURL:      strings.TrimRight(cfg.AppBaseURL, "/"),
+		allowedDomains:  cfg.AllowedEmailDomains,
+		mailTimeout:     cfg.MailTimeout,
+		resetTTL:        cfg.PasswordResetTokenTTL,
+		verificationTTL: cfg.EmailVerificationTokenTTL,
+	}
+}
+
+// ForgotPassword mails a password reset link. It answers the same way whether
+// or not the email belongs to an account, so it cannot be used to probe for
+// registered addresses. Requests are throttled per client IP, and at most one
+// link a minute is mailed to an address.
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload models.EmailPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	email := normalizeEmail(payload.Email)
+	if email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	send, ok := h.reserveMail(w, r, email)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	err := h.db.Collection("users").FindOne(context.TODO(), bson.M{"email": email}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err == nil && send {
+		token, err := h.tokens.Issue(context.TODO(), user.ID, models.TokenPurposePasswordReset, h.resetTTL)
+		if err != nil {
+			log.Printf("Failed to issue password reset token for user %s: %v", user.ID.Hex(), err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		link := h.appBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+		h.sendMail(mail.Message{
+			To:      user.Email,
+			Subject: "Reset password akun JTE",
+			Body: fmt.Sprintf("Halo,\n\nKami menerima permintaan untuk mengatur ulang password akun Anda.\n"+
+				"Buka tautan berikut dalam %d menit untuk membuat password baru:\n\n%s\n\n"+
+				"Jika Anda tidak meminta reset password, abaikan email ini.\n",
+				int(h.resetTTL.Minutes()), link),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Jika email terdaftar, tautan reset password telah dikirim.",
+	})
+}
+
+// ResetPassword sets a new password using a reset token. The token can only
+// be used once, and every session of the account is revoked.
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload models.ResetPasswordPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if payload.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+	// The email is unknown until the token is redeemed, so the email rule
+	// cannot be checked here.
+	if problems := h.passwords.Validate(payload.Password, ""); len(problems) > 0 {
+		writePasswordProblems(w, problems)
+		return
+	}
+
+	hashedPassword, err := h.passwords.Hash(payload.Password)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userToken, err := h.tokens.Consume(context.TODO(), payload.Token, models.TokenPurposePasswordReset)
+	if errors.Is(err, usertokens.ErrInvalidToken) {
+		http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.db.Collection("users").UpdateOne(context.TODO(),
+		bson.M{"_id": userToken.UserID},
+		bson.M{"$set": bson.M{"password": string(hashedPassword)}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.sessions.RevokeAll(context.TODO(), userToken.UserID, models.SessionRevokedPassword); err != nil {
+		log.Printf("Failed to revoke sessions of user %s after password reset: %v", userToken.UserID.Hex(), err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password berhasil diubah. Silakan masuk kembali."})
+}
+
+// ChangePassword sets a new password for the caller after checking the
+// current one. Wrong current passwords count against the account's login
+// throttle, so a stolen access token cannot be used to guess the password.
+// Every other session of the account is revoked.
+func (h *AccountHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.ChangePasswordPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": claims.UserID}).Decode(&user); err != nil {
+		http.Error(w, "User not found or invalid", http.StatusUnauthorized)
+		return
+	}
+
+	throttleKey := throttle.AccountKey(user.Email)
+	wait, err := h.throttle.Reserve(r.Context(), throttleKey, throttle.AccountLimits)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.CurrentPassword)); err != nil {
+		http.Error(w, "Current password is incorrect", http.StatusForbidden)
+		return
+	}
+	if err := h.throttle.Release(r.Context(), throttleKey); err != nil {
+		log.Printf("Failed to release password attempt for %s: %v", user.Email, err)
+	}
+	if payload.NewPassword == payload.CurrentPassword {
+		writePasswordProblems(w, []string{"New password must differ from the current password"})
+		return
+	}
+	if problems := h.passwords.Validate(payload.NewPassword, user.Email); len(problems) > 0 {
+		writePasswordProblems(w, problems)
+		return
+	}
+
+	hashedPassword, err := h.passwords.Hash(payload.NewPassword)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = h.db.Collection("users").UpdateOne(context.TODO(),
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"password": string(hashedPassword)}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	revoked, err := h.sessions.RevokeAll(context.TODO(), user.ID, models.SessionRevokedPassword, claims.SessionID)
+	if err != nil {
+		log.Printf("Failed to revoke sessions of user %s after password change: %v", user.ID.Hex(), err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":         "Password berhasil diubah.",
+		"revokedSessions": revoked,
+	})
+}
+
+// VerifyEmail marks the account of a verification token as verified.
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var payload models.VerifyEmailPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if payload.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	userToken, err := h.tokens.Consume(context.TODO(), payload.Token, models.TokenPurposeEmailVerification)
+	if errors.Is(err, usertokens.ErrInvalidToken) {
+		http.Error(w, "Invalid or expired verification token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.db.Collection("users").UpdateOne(context.TODO(),
+		bson.M{"_id": userToken.UserID},
+		bson.M{"$set": bson.M{"email_verified": true, "email_verified_at": time.Now()}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Invalid or expired verification token", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email berhasil diverifikasi. Silakan masuk."})
+}
+
+// ResendVerification mails a new verification link to an unverified account.
+// Like ForgotPassword, it answers the same way for unknown addresses and is
+// throttled the same way.
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var payload models.EmailPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	email := normalizeEmail(payload.Email)
+	if email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	send, ok := h.reserveMail(w, r, email)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	err := h.db.Collection("users").FindOne(context.TODO(), bson.M{"email": email, "email_verified": false}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err == nil && send {
+		if err := h.sendVerification(context.TODO(), user); err != nil {
+			log.Printf("Failed to issue verification token for user %s: %v", user.ID.Hex(), err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Jika akun belum diverifikasi, tautan verifikasi telah dikirim.",
+	})
+}
+
+// reserveMail throttles a request to mail a link to email. Too many requests
+// from the client IP are refused with 429. An address that was mailed within
+// the last minute is not mailed again, but the caller still answers as usual
+// so the cool-down does not reveal whether the address is registered.
+func (h *AccountHandler) reserveMail(w http.ResponseWriter, r *http.Request, email string) (send, ok bool) {
+	wait, err := h.throttle.Reserve(r.Context(), throttle.IPKey(h.proxies.clientIP(r)), throttle.IPLimits)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false, false
+	}
+	if wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return false, false
+	}
+
+	wait, err = h.throttle.Reserve(r.Context(), throttle.MailKey(email), throttle.MailLimits)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false, false
+	}
+	return wait == 0, true
+}
+
+// sendVerification issues a verification token for the user and mails the link.
+func (h *AccountHandler) sendVerification(ctx context.Context, user models.User) error {
+	token, err := h.tokens.Issue(ctx, user.ID, models.TokenPurposeEmailVerification, h.verificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := h.appBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+	h.sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "Verifikasi email akun JTE",
+		Body: fmt.Sprintf("Halo,\n\nTerima kasih telah mendaftar. Buka tautan berikut dalam %d jam untuk memverifikasi email Anda:\n\n%s\n\n"+
+			"Jika Anda tidak mendaftar, abaikan email ini.\n",
+			int(h.verificationTTL.Hours()), link),
+	})
+	return nil
+}
+
+// validateEmail normalizes an email address for registration and checks that
+// it is well-formed and belongs to an allowed domain.
+func (h *AccountHandler) validateEmail(email string) (string, error) {
+	email = normalizeEmail(email)
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", errors.New("not a valid email address")
+	}
+
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for _, allowed := range h.allowedDomains {
+		if domain == allowed {
+			return email, nil
+		}
+	}
+	return "", fmt.Errorf("domain must be one of %s", strings.Join(h.allowedDomains, ", "))
+}
+
+// writePasswordProblems answers with the password policy rules a new
+// password breaks.
+func writePasswordProblems(w http.ResponseWriter, problems []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  http.StatusBadRequest,
+		"message": "Password tidak memenuhi kebijakan password.",
+		"errors":  problems,
+	})
+}
+
+// normalizeEmail trims and lowercases an email address, as stored in users.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// sendMail sends the message in the background so the response time does not
+// reveal whether an email was sent.
+func (h *AccountHandler) sendMail(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), h.mailTimeout)
+		defer cancel()
+		if err := h.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/mail"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/usertokens"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// discardMailer accepts every message without sending it.
+type discardMailer struct{}
+
+func (discardMailer) Send(context.Context, mail.Message) error { return nil }
+
+func newTestAccountHandler(t *testing.T) (*AccountHandler, models.User) {
+	db := testDB(t)
+	user := models.User{ID: primitive.NewObjectID(), Email: "student@unsrat.ac.id", Role: auth.RoleStudent}
+	if _, err := db.Collection("users").InsertOne(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Config{
+		PasswordResetTokenTTL:     time.Hour,
+		EmailVerificationTokenTTL: time.Hour,
+		MailTimeout:               time.Second,
+	}
+	return NewAccountHandler(db, sessions.NewStore(db, time.Hour), discardMailer{}, nil, cfg), user
+}
+
+func postEmail(handle http.HandlerFunc, path, email, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(fmt.Sprintf(`{"email":%q}`, email)))
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+	handle(rec, req)
+	return rec
+}
+
+func TestForgotPasswordMailsAnAddressOnceAMinute(t *testing.T) {
+	h, user := newTestAccountHandler(t)
+
+	for i := 0; i < 2; i++ {
+		if rec := postEmail(h.ForgotPassword, "/api/forgot-password", user.Email, "192.0.2.1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d, want %d", i, rec.Code, http.StatusOK)
+		}
+	}
+
+	issued, err := h.db.Collection(usertokens.Collection).CountDocuments(context.Background(), bson.M{"user_id": user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued != 1 {
+		t.Errorf("%d reset tokens issued, want 1", issued)
+	}
+}
+
+func TestForgotPasswordIsThrottledPerIP(t *testing.T) {
+	h, _ := newTestAccountHandler(t)
+
+	// Every request names a different address, so only the IP counter applies.
+	for i := 0; i < 12; i++ {
+		rec := postEmail(h.ForgotPassword, "/api/forgot-password", fmt.Sprintf("unknown%d@unsrat.ac.id", i), "192.0.2.2")
+		want := http.StatusOK
+		if i == 11 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Fatalf("request %d: got %d, want %d", i, rec.Code, want)
+		}
+	}
+}
+
+func TestResendVerificationMailsAnAddressOnceAMinute(t *testing.T) {
+	h, user := newTestAccountHandler(t)
+
+	for i := 0; i < 2; i++ {
+		if rec := postEmail(h.ResendVerification, "/api/verify-email/resend", user.Email, "192.0.2.3"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d, want %d", i, rec.Code, http.StatusOK)
+		}
+	}
+
+	issued, err := h.db.Collection(usertokens.Collection).CountDocuments(context.Background(), bson.M{"user_id": user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued != 1 {
+		t.Errorf("%d verification tokens issued, want 1", issued)
+	}
+}
@@ -0,0 +1,519 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/database"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/throttle"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/usertokens"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+	// userDetailHistory is how many reservations and login logs the user
+	// detail view includes.
+	userDetailHistory = 20
+	maxDisableReason  = 500
+)
+
+// errLastSuperAdmin is returned when an action would leave no enabled superadmin.
+var errLastSuperAdmin = errors.New("the last superadmin cannot be demoted, disabled or deleted")
+
+// AdminUserHandler lets admins look up users, change roles and disable or
+// delete accounts.
+type AdminUserHandler struct {
+	db       *mongo.Database
+	sessions *sessions.Store
+	throttle *throttle.Store
+}
+
+// NewAdminUserHandler creates a new AdminUserHandler.
+func NewAdminUserHandler(db *mongo.Database, sessionStore *sessions.Store) *AdminUserHandler {
+	return &AdminUserHandler{db: db, sessions: sessionStore, throttle: throttle.NewStore(db)}
+}
+
+// ListUsers searches users by email, name or NIM/NIP (q) and filters by role,
+// userType and disabled. Results are paginated with page and limit. Deleted
+// accounts are left out unless includeDeleted=true.
+func (h *AdminUserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := positiveIntParam(query.Get("page"), 1)
+	if err != nil {
+		http.Error(w, "page must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	limit, err := positiveIntParam(query.Get("limit"), defaultUserPageSize)
+	if err != nil || limit > maxUserPageSize {
+		http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxUserPageSize), http.StatusBadRequest)
+		return
+	}
+
+	filter := bson.M{}
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(q), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"email": pattern},
+			bson.M{"full_name": pattern},
+			bson.M{"identity_number": pattern},
+		}
+	}
+	if role := query.Get("role"); role != "" {
+		if !auth.IsRole(role) {
+			http.Error(w, "Unknown role", http.StatusBadRequest)
+			return
+		}
+		filter["role"] = role
+	}
+	if userType := query.Get("userType"); userType != "" {
+		filter["user_type"] = userType
+	}
+	switch query.Get("disabled") {
+	case "":
+	case "true":
+		filter["disabled"] = true
+	case "false":
+		filter["disabled"] = bson.M{"$ne": true}
+	default:
+		http.Error(w, "disabled must be true or false", http.StatusBadRequest)
+		return
+	}
+	if query.Get("includeDeleted") != "true" {
+		filter["deleted_at"] = nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := h.db.Collection("users")
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		return
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	list := models.UserList{Users: []models.User{}, Page: page, Limit: limit, Total: total}
+	if err := cursor.All(ctx, &list.Users); err != nil {
+		http.Error(w, "Failed to parse users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetUser returns a user with their latest reservations and login attempts.
+func (h *AdminUserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	detail := models.UserDetail{User: user, Reservations: []models.Reservation{}, LoginLogs: []models.LoginLog{}}
+
+	reservationOptions := options.Find().SetSort(bson.D{{Key: "start_time", Value: -1}}).SetLimit(userDetailHistory)
+	cursor, err := h.db.Collection("reservations").Find(ctx, bson.M{"user_id": user.ID}, reservationOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(ctx, &detail.Reservations); err != nil {
+		http.Error(w, "Failed to parse reservations", http.StatusInternalServerError)
+		return
+	}
+
+	logOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(userDetailHistory)
+	cursor, err = h.db.Collection("login_logs").Find(ctx, bson.M{"user_id": user.ID}, logOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve login logs", http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(ctx, &detail.LoginLogs); err != nil {
+		http.Error(w, "Failed to parse login logs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// ChangeRole sets a user's role. Only a superadmin may grant admin or
+// superadmin, or change the role of an admin. The user's sessions are
+// revoked so the new role applies immediately.
+func (h *AdminUserHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.ChangeRolePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !auth.IsRole(payload.Role) {
+		http.Error(w, "role must be one of student, admin or superadmin", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == claims.UserID {
+		http.Error(w, "You cannot change your own role", http.StatusForbidden)
+		return
+	}
+	if user.DeletedAt != nil {
+		http.Error(w, "User has been deleted", http.StatusConflict)
+		return
+	}
+	if !auth.CanAssignRole(claims.Role, user.Role, payload.Role) {
+		http.Error(w, "Forbidden: only a superadmin can grant or remove admin roles", http.StatusForbidden)
+		return
+	}
+	if user.Role == payload.Role {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
+		return
+	}
+	if user.Role == auth.RoleSuperAdmin {
+		lock, err := h.ensureAnotherSuperAdmin(user.ID)
+		if err != nil {
+			h.writeSuperAdminError(w, err)
+			return
+		}
+		defer lock.Release(context.Background())
+	}
+
+	user.Role = payload.Role
+	if !h.updateUser(w, user.ID, bson.M{"$set": bson.M{"role": payload.Role, "updated_at": time.Now()}}) {
+		return
+	}
+	h.revokeSessions(user.ID, models.SessionRevokedRole)
+	log.Printf("Admin %s changed role of user %s to %s", claims.UserID.Hex(), user.ID.Hex(), payload.Role)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DisableUser disables an account and signs it out everywhere. Admins cannot
+// disable themselves, and only a superadmin can disable another admin.
+func (h *AdminUserHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.DisableUserPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if len(payload.Reason) > maxDisableReason {
+		http.Error(w, fmt.Sprintf("reason must be at most %d characters", maxDisableReason), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.findManageableUser(w, r, claims)
+	if !ok {
+		return
+	}
+	if user.DeletedAt != nil {
+		http.Error(w, "User has been deleted", http.StatusConflict)
+		return
+	}
+	if user.Role == auth.RoleSuperAdmin && !user.Disabled {
+		lock, err := h.ensureAnotherSuperAdmin(user.ID)
+		if err != nil {
+			h.writeSuperAdminError(w, err)
+			return
+		}
+		defer lock.Release(context.Background())
+	}
+
+	now := time.Now()
+	set := bson.M{"disabled": true, "disabled_at": now, "updated_at": now}
+	update := bson.M{"$set": set}
+	if payload.Reason != "" {
+		set["disabled_reason"] = payload.Reason
+	} else {
+		update["$unset"] = bson.M{"disabled_reason": ""}
+	}
+	if !h.updateUser(w, user.ID, update) {
+		return
+	}
+	h.revokeSessions(user.ID, models.SessionRevokedDisabled)
+	log.Printf("Admin %s disabled user %s", claims.UserID.Hex(), user.ID.Hex())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User disabled"})
+}
+
+// EnableUser re-enables a disabled account. Deleted accounts stay disabled.
+func (h *AdminUserHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	user, ok := h.findManageableUser(w, r, claims)
+	if !ok {
+		return
+	}
+	if user.DeletedAt != nil {
+		http.Error(w, "User has been deleted", http.StatusConflict)
+		return
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"disabled": false, "updated_at": time.Now()},
+		"$unset": bson.M{"disabled_at": "", "disabled_reason": ""},
+	}
+	if !h.updateUser(w, user.ID, update) {
+		return
+	}
+	log.Printf("Admin %s enabled user %s", claims.UserID.Hex(), user.ID.Hex())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User enabled"})
+}
+
+// DeleteUser deletes an account by anonymizing it: the email, password,
+// profile and two-factor secrets are removed and the account is disabled.
+// The document is kept so reservations still refer to a user, and the
+// account's login logs lose their email and IP.
+func (h *AdminUserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	user, ok := h.findManageableUser(w, r, claims)
+	if !ok {
+		return
+	}
+	if user.DeletedAt != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Role == auth.RoleSuperAdmin && !user.Disabled {
+		lock, err := h.ensureAnotherSuperAdmin(user.ID)
+		if err != nil {
+			h.writeSuperAdminError(w, err)
+			return
+		}
+		defer lock.Release(context.Background())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	anonymousEmail := fmt.Sprintf("deleted-%s@deleted.invalid", user.ID.Hex())
+	update := bson.M{
+		"$set": bson.M{
+			"email":          anonymousEmail,
+			"password":       "",
+			"role":           auth.RoleStudent,
+			"email_verified": false,
+			"totp_enabled":   false,
+			"disabled":       true,
+			"disabled_at":    now,
+			"deleted_at":     now,
+			"updated_at":     now,
+		},
+		"$unset": bson.M{
+			"email_verified_at":    "",
+			"full_name":            "",
+			"user_type":            "",
+			"identity_number":      "",
+			"phone":                "",
+			"study_program":        "",
+			"faculty":              "",
+			"avatar_url":           "",
+			"disabled_reason":      "",
+			"totp_secret":          "",
+			"totp_pending_secret":  "",
+			"totp_last_step":       "",
+			"recovery_code_hashes": "",
+		},
+	}
+	if _, err := h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, update); err != nil {
+		log.Printf("ERROR: Failed to anonymize user %s: %v", user.ID.Hex(), err)
+		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		return
+	}
+
+	h.revokeSessions(user.ID, models.SessionRevokedDeleted)
+	if _, err := h.db.Collection(usertokens.Collection).DeleteMany(ctx, bson.M{"user_id": user.ID}); err != nil {
+		log.Printf("Failed to delete tokens of user %s: %v", user.ID.Hex(), err)
+	}
+	if err := h.throttle.Reset(ctx, throttle.AccountKey(user.Email)); err != nil {
+		log.Printf("Failed to reset login throttle of user %s: %v", user.ID.Hex(), err)
+	}
+	_, err := h.db.Collection("login_logs").UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"user_id": user.ID}, bson.M{"email": user.Email}}},
+		bson.M{"$set": bson.M{"email": anonymousEmail, "user_agent": ""}, "$unset": bson.M{"ip": ""}},
+	)
+	if err != nil {
+		log.Printf("Failed to anonymize login logs of user %s: %v", user.ID.Hex(), err)
+	}
+	log.Printf("Admin %s deleted user %s", claims.UserID.Hex(), user.ID.Hex())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted"})
+}
+
+// findUser loads the user named by the {id} path variable.
+func (h *AdminUserHandler) findUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	var user models.User
+	userID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid User ID format", http.StatusBadRequest)
+		return user, false
+	}
+
+	err = h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return user, false
+	}
+	return user, true
+}
+
+// findManageableUser loads the user named by the {id} path variable and
+// checks the caller may act on them: not themselves, and only a superadmin
+// may act on an admin or superadmin account.
+func (h *AdminUserHandler) findManageableUser(w http.ResponseWriter, r *http.Request, claims *auth.Claims) (models.User, bool) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return user, false
+	}
+	if user.ID == claims.UserID {
+		http.Error(w, "You cannot do this to your own account", http.StatusForbidden)
+		return user, false
+	}
+	if !auth.CanAssignRole(claims.Role, user.Role, auth.RoleStudent) {
+		http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+		return user, false
+	}
+	return user, true
+}
+
+// updateUser applies update to the user, answering with an error on failure.
+func (h *AdminUserHandler) updateUser(w http.ResponseWriter, userID primitive.ObjectID, update bson.M) bool {
+	if _, err := h.db.Collection("users").UpdateOne(context.TODO(), bson.M{"_id": userID}, update); err != nil {
+		log.Printf("ERROR: Failed to update user %s: %v", userID.Hex(), err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// superAdminLockTTL bounds how long a demotion, disable or delete of a
+// superadmin can hold the superadmin lock.
+const superAdminLockTTL = 10 * time.Second
+
+// ensureAnotherSuperAdmin returns errLastSuperAdmin unless an enabled
+// superadmin other than userID exists. On success it returns the superadmin
+// lock, which the caller holds until the account has been updated, so two
+// superadmins cannot remove each other at the same time.
+func (h *AdminUserHandler) ensureAnotherSuperAdmin(userID primitive.ObjectID) (*database.Lock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lock, err := database.AcquireLock(ctx, h.db, "superadmins", superAdminLockTTL)
+	if err != nil {
+		return nil, err
+	}
+	count, err := h.db.Collection("users").CountDocuments(ctx, bson.M{
+		"_id":      bson.M{"$ne": userID},
+		"role":     auth.RoleSuperAdmin,
+		"disabled": bson.M{"$ne": true},
+	})
+	if err == nil && count == 0 {
+		err = errLastSuperAdmin
+	}
+	if err != nil {
+		lock.Release(context.Background())
+		return nil, err
+	}
+	return lock, nil
+}
+
+func (h *AdminUserHandler) writeSuperAdminError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errLastSuperAdmin) {
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, database.ErrLockTimeout) {
+		http.Error(w, "Another change to a superadmin is in progress, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+}
+
+// revokeSessions signs the user out everywhere. Failures are logged; the
+// account check in middleware.Auth still stops disabled accounts.
+func (h *AdminUserHandler) revokeSessions(userID primitive.ObjectID, reason string) {
+	if _, err := h.sessions.RevokeAll(context.TODO(), userID, reason); err != nil {
+		log.Printf("Failed to revoke sessions of user %s: %v", userID.Hex(), err)
+	}
+}
+
+// positiveIntParam parses a positive integer query parameter, returning
+// fallback when it is empty.
+func positiveIntParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.New("not a positive integer")
+	}
+	return n, nil
+}
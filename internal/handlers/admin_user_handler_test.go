@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDisableUserKeepsOneSuperAdminUnderConcurrentRequests(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	// Two superadmins try to disable each other at the same time.
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+	for i, id := range ids {
+		user := models.User{ID: id, Email: fmt.Sprintf("superadmin%d@unsrat.ac.id", i), Role: auth.RoleSuperAdmin}
+		if _, err := db.Collection("users").InsertOne(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewAdminUserHandler(db, sessions.NewStore(db, time.Hour))
+
+	var wg sync.WaitGroup
+	codes := make([]int, len(ids))
+	for i := range ids {
+		actor, target := ids[i], ids[1-i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claims := &auth.Claims{UserID: actor, Role: auth.RoleSuperAdmin}
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+target.Hex()+"/disable", strings.NewReader("{}"))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.ClaimsKey, claims))
+			req = mux.SetURLVars(req, map[string]string{"id": target.Hex()})
+			rec := httptest.NewRecorder()
+			h.DisableUser(rec, req)
+			codes[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	enabled, err := db.Collection("users").CountDocuments(ctx, bson.M{"role": auth.RoleSuperAdmin, "disabled": bson.M{"$ne": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled != 1 {
+		t.Errorf("%d superadmins left enabled (statuses %v), want 1", enabled, codes)
+	}
+}
+
+func TestAdminCannotDisableOrDeleteAnotherAdmin(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	h := NewAdminUserHandler(db, sessions.NewStore(db, time.Hour))
+
+	tests := []struct {
+		name       string
+		actorRole  string
+		targetRole string
+		deleteUser bool
+		want       int
+	}{
+		{"admin disables admin", auth.RoleAdmin, auth.RoleAdmin, false, http.StatusForbidden},
+		{"admin deletes admin", auth.RoleAdmin, auth.RoleAdmin, true, http.StatusForbidden},
+		{"admin disables superadmin", auth.RoleAdmin, auth.RoleSuperAdmin, false, http.StatusForbidden},
+		{"admin disables student", auth.RoleAdmin, auth.RoleStudent, false, http.StatusOK},
+		{"superadmin disables admin", auth.RoleSuperAdmin, auth.RoleAdmin, false, http.StatusOK},
+		{"superadmin deletes admin", auth.RoleSuperAdmin, auth.RoleAdmin, true, http.StatusOK},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.User{ID: primitive.NewObjectID(), Email: fmt.Sprintf("target%d@unsrat.ac.id", i), Role: tt.targetRole}
+			if _, err := db.Collection("users").InsertOne(ctx, target); err != nil {
+				t.Fatal(err)
+			}
+
+			claims := &auth.Claims{UserID: primitive.NewObjectID(), Role: tt.actorRole}
+			method, path, handle := http.MethodPost, "/api/admin/users/"+target.ID.Hex()+"/disable", h.DisableUser
+			if tt.deleteUser {
+				method, path, handle = http.MethodDelete, "/api/admin/users/"+target.ID.Hex(), h.DeleteUser
+			}
+			req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.ClaimsKey, claims))
+			req = mux.SetURLVars(req, map[string]string{"id": target.ID.Hex()})
+			rec := httptest.NewRecorder()
+			handle(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status: got %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+			var stored models.User
+			if err := db.Collection("users").FindOne(ctx, bson.M{"_id": target.ID}).Decode(&stored); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == http.StatusForbidden && (stored.Disabled || stored.Role != tt.targetRole) {
+				t.Errorf("refused request changed the target: disabled %v, role %q", stored.Disabled, stored.Role)
+			}
+		})
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -25,18 +25,10 @@ func (h *AnnouncementHandler) GetAnnouncements(w http.ResponseWriter, r *http.Re
 	collection := h.db.Collection("announcements")
 	filter := bson.M{"announcement_type": "public"}
 
-	// Check for an authorization token
-	authHeader := r.Header.Get("Authorization")
-	if authHeader != "" {
-		parts := strings.Split(authHeader, " ")
-		if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
-			tokenString := parts[1]
-
-			claims, err := auth.ValidateJWT(tokenString)
-			if err == nil && claims.Role == "admin" {
-				filter = bson.M{}
-			}
-		}
+	// Claims are only present for signed-in users (see middleware.OptionalAuth).
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if ok && claims != nil && claims.Can(auth.PermViewPrivateAnnouncements) {
+		filter = bson.M{}
 	}
 
 	// Sort by most recent
@@ -0,0 +1,718 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/labels"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxSerialNumberLength = 100
+	maxUnitHistory        = 100
+	maxLabelsPerSheet     = 120
+	defaultLabelColumns   = 3
+	maxLabelColumns       = 8
+)
+
+// assetTagPattern keeps tags short enough to fit a Code 128 label.
+var assetTagPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{0,15}$`)
+
+// unitTransitions lists, for each target status, the statuses a unit must
+// currently be in for an admin to move it there by hand. Units go on and off
+// loan through loan pickup and return.
+var unitTransitions = map[string][]string{
+	models.AssetUnitInRepair:  {models.AssetUnitAvailable},
+	models.AssetUnitAvailable: {models.AssetUnitInRepair},
+	models.AssetUnitRetired:   {models.AssetUnitAvailable, models.AssetUnitInRepair},
+}
+
+// AssetUnitHandler handles admin management of the tagged units of
+// inventory items and their labels.
+type AssetUnitHandler struct {
+	db *mongo.Database
+}
+
+// NewAssetUnitHandler creates a new AssetUnitHandler.
+func NewAssetUnitHandler(db *mongo.Database) *AssetUnitHandler {
+	return &AssetUnitHandler{db: db}
+}
+
+// CreateUnit tags a new unit of an item. An item cannot have more units in
+// service than its total quantity.
+func (h *AssetUnitHandler) CreateUnit(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	itemID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Item ID format", http.StatusBadRequest)
+		return
+	}
+
+	var payload models.CreateAssetUnitPayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	payload.AssetTag = strings.ToUpper(strings.TrimSpace(payload.AssetTag))
+	payload.SerialNumber = strings.TrimSpace(payload.SerialNumber)
+	payload.Condition = strings.ToLower(strings.TrimSpace(payload.Condition))
+	payload.Notes = strings.TrimSpace(payload.Notes)
+	if payload.Condition == "" {
+		payload.Condition = models.ItemConditionGood
+	}
+
+	switch {
+	case !assetTagPattern.MatchString(payload.AssetTag):
+		http.Error(w, "assetTag must be 1-16 letters, digits, '-' or '_'", http.StatusBadRequest)
+		return
+	case utf8.RuneCountInString(payload.SerialNumber) > maxSerialNumberLength:
+		http.Error(w, fmt.Sprintf("serialNumber must be at most %d characters", maxSerialNumberLength), http.StatusBadRequest)
+		return
+	case !slices.Contains(itemConditions, payload.Condition):
+		http.Error(w, "condition must be one of good, fair or damaged", http.StatusBadRequest)
+		return
+	case utf8.RuneCountInString(payload.Notes) > maxLoanTextLength:
+		http.Error(w, fmt.Sprintf("notes must be at most %d characters", maxLoanTextLength), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The item lock keeps the unit count and the item's total quantity
+	// consistent with UpdateItem.
+	lock, err := lockItem(ctx, h.db, itemID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock item %s: %v", itemID.Hex(), err)
+		http.Error(w, "The item is busy, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	defer lock.Release(context.Background())
+
+	var item models.InventoryItem
+	err = h.db.Collection("inventory_items").FindOne(ctx, bson.M{"_id": itemID, "deleted_at": nil}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Item not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve item", http.StatusInternalServerError)
+		return
+	}
+	tracked, err := trackedUnits(ctx, h.db, itemID)
+	if err != nil {
+		http.Error(w, "Failed to count item units", http.StatusInternalServerError)
+		return
+	}
+	if tracked >= int64(item.TotalQuantity) {
+		http.Error(w, fmt.Sprintf("All %d unit(s) of the item are already tagged; raise its totalQuantity first", item.TotalQuantity), http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	unit := models.AssetUnit{
+		ID:           primitive.NewObjectID(),
+		ItemID:       itemID,
+		AssetTag:     payload.AssetTag,
+		SerialNumber: payload.SerialNumber,
+		Status:       models.AssetUnitAvailable,
+		Condition:    payload.Condition,
+		Notes:        payload.Notes,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if _, err := h.db.Collection("asset_units").InsertOne(ctx, unit); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Asset tag or serial number already in use", http.StatusConflict)
+			return
+		}
+		log.Printf("ERROR: Failed to insert asset unit: %v", err)
+		http.Error(w, "Failed to create unit", http.StatusInternalServerError)
+		return
+	}
+	recordUnitEvents(ctx, h.db, models.AssetUnitEvent{
+		UnitID:    unit.ID,
+		Type:      models.AssetUnitEventCreated,
+		Condition: unit.Condition,
+		Notes:     unit.Notes,
+		ActorID:   &claims.UserID,
+		CreatedAt: now,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(unit)
+}
+
+// ListItemUnits returns the units of an item ordered by asset tag,
+// optionally filtered by status.
+func (h *AssetUnitHandler) ListItemUnits(w http.ResponseWriter, r *http.Request) {
+	itemID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Item ID format", http.StatusBadRequest)
+		return
+	}
+
+	filter := bson.M{"item_id": itemID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+
+	units, err := h.findUnits(context.TODO(), filter)
+	if err != nil {
+		http.Error(w, "Failed to retrieve units", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(units)
+}
+
+// GetUnit returns a unit with its history.
+func (h *AssetUnitHandler) GetUnit(w http.ResponseWriter, r *http.Request) {
+	unitID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Unit ID format", http.StatusBadRequest)
+		return
+	}
+	h.writeUnitDetail(w, bson.M{"_id": unitID})
+}
+
+// GetUnitByTag returns the unit with the scanned asset tag, with its history.
+func (h *AssetUnitHandler) GetUnitByTag(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToUpper(strings.TrimSpace(mux.Vars(r)["tag"]))
+	h.writeUnitDetail(w, bson.M{"asset_tag": tag})
+}
+
+func (h *AssetUnitHandler) writeUnitDetail(w http.ResponseWriter, filter bson.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var detail models.AssetUnitDetail
+	err := h.db.Collection("asset_units").FindOne(ctx, filter).Decode(&detail.AssetUnit)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Unit not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve unit", http.StatusInternalServerError)
+		return
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(maxUnitHistory)
+	cursor, err := h.db.Collection("asset_unit_events").Find(ctx, bson.M{"unit_id": detail.ID}, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve unit history", http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(ctx, &detail.History); err != nil {
+		http.Error(w, "Failed to parse unit history", http.StatusInternalServerError)
+		return
+	}
+	if detail.History == nil {
+		detail.History = []models.AssetUnitEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// UpdateUnit edits a unit's serial number, condition or notes. A condition
+// change is recorded in the unit's history.
+func (h *AssetUnitHandler) UpdateUnit(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	unitID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Unit ID format", http.StatusBadRequest)
+		return
+	}
+
+	var payload models.UpdateAssetUnitPayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	set, unset := bson.M{}, bson.M{}
+	if payload.SerialNumber != nil {
+		serial := strings.TrimSpace(*payload.SerialNumber)
+		if utf8.RuneCountInString(serial) > maxSerialNumberLength {
+			http.Error(w, fmt.Sprintf("serialNumber must be at most %d characters", maxSerialNumberLength), http.StatusBadRequest)
+			return
+		}
+		if serial == "" {
+			unset["serial_number"] = ""
+		} else {
+			set["serial_number"] = serial
+		}
+	}
+	if payload.Notes != nil {
+		notes := strings.TrimSpace(*payload.Notes)
+		if utf8.RuneCountInString(notes) > maxLoanTextLength {
+			http.Error(w, fmt.Sprintf("notes must be at most %d characters", maxLoanTextLength), http.StatusBadRequest)
+			return
+		}
+		if notes == "" {
+			unset["notes"] = ""
+		} else {
+			set["notes"] = notes
+		}
+	}
+	if payload.Condition != nil {
+		condition := strings.ToLower(strings.TrimSpace(*payload.Condition))
+		if !slices.Contains(itemConditions, condition) {
+			http.Error(w, "condition must be one of good, fair or damaged", http.StatusBadRequest)
+			return
+		}
+		set["condition"] = condition
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		http.Error(w, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	set["updated_at"] = now
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	// Return the document as it was, to tell whether the condition changed.
+	var before models.AssetUnit
+	err = h.db.Collection("asset_units").FindOneAndUpdate(ctx, bson.M{"_id": unitID}, update).Decode(&before)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Unit not found", http.StatusNotFound)
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Serial number already in use", http.StatusConflict)
+			return
+		}
+		log.Printf("ERROR: Failed to update asset unit %s: %v", unitID.Hex(), err)
+		http.Error(w, "Failed to update unit", http.StatusInternalServerError)
+		return
+	}
+	if condition, ok := set["condition"].(string); ok && condition != before.Condition {
+		recordUnitEvents(ctx, h.db, models.AssetUnitEvent{
+			UnitID:    unitID,
+			Type:      models.AssetUnitEventConditionChanged,
+			Condition: condition,
+			ActorID:   &claims.UserID,
+			CreatedAt: now,
+		})
+	}
+
+	h.writeUnitDetail(w, bson.M{"_id": unitID})
+}
+
+// StartRepair sends an available unit to repair.
+func (h *AssetUnitHandler) StartRepair(w http.ResponseWriter, r *http.Request) {
+	h.changeUnitStatus(w, r, models.AssetUnitInRepair, models.AssetUnitEventRepairStarted)
+}
+
+// FinishRepair puts a unit back into service, optionally with its new
+// condition.
+func (h *AssetUnitHandler) FinishRepair(w http.ResponseWriter, r *http.Request) {
+	h.changeUnitStatus(w, r, models.AssetUnitAvailable, models.AssetUnitEventRepairFinished)
+}
+
+// RetireUnit takes a unit out of service for good.
+func (h *AssetUnitHandler) RetireUnit(w http.ResponseWriter, r *http.Request) {
+	h.changeUnitStatus(w, r, models.AssetUnitRetired, models.AssetUnitEventRetired)
+}
+
+// changeUnitStatus moves a unit to status, enforcing the allowed transitions
+// and recording the change in the unit's history.
+func (h *AssetUnitHandler) changeUnitStatus(w http.ResponseWriter, r *http.Request, status, eventType string) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	unitID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Unit ID format", http.StatusBadRequest)
+		return
+	}
+
+	var payload models.AssetUnitActionPayload
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	payload.Condition = strings.ToLower(strings.TrimSpace(payload.Condition))
+	payload.Notes = strings.TrimSpace(payload.Notes)
+	switch {
+	case payload.Condition != "" && eventType != models.AssetUnitEventRepairFinished:
+		http.Error(w, "condition is only recorded when a repair is finished", http.StatusBadRequest)
+		return
+	case payload.Condition != "" && !slices.Contains(itemConditions, payload.Condition):
+		http.Error(w, "condition must be one of good, fair or damaged", http.StatusBadRequest)
+		return
+	case utf8.RuneCountInString(payload.Notes) > maxLoanTextLength:
+		http.Error(w, fmt.Sprintf("notes must be at most %d characters", maxLoanTextLength), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var unit models.AssetUnit
+	if err := h.db.Collection("asset_units").FindOne(ctx, bson.M{"_id": unitID}).Decode(&unit); err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Unit not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve unit", http.StatusInternalServerError)
+		return
+	}
+	if !slices.Contains(unitTransitions[status], unit.Status) {
+		http.Error(w, fmt.Sprintf("Cannot change unit from %s to %s", unit.Status, status), http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"status": status, "updated_at": now}
+	if payload.Condition != "" {
+		set["condition"] = payload.Condition
+	}
+	if status == models.AssetUnitRetired {
+		set["retired_at"] = now
+	}
+
+	// Filtering on the current status makes the transition atomic, so a unit
+	// handed over in the meantime is not sent to repair.
+	result, err := h.db.Collection("asset_units").UpdateOne(ctx, bson.M{"_id": unit.ID, "status": unit.Status}, bson.M{"$set": set})
+	if err != nil {
+		log.Printf("ERROR: Failed to update asset unit %s: %v", unit.ID.Hex(), err)
+		http.Error(w, "Failed to update unit", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Unit was modified by another request, please retry", http.StatusConflict)
+		return
+	}
+	recordUnitEvents(ctx, h.db, models.AssetUnitEvent{
+		UnitID:    unit.ID,
+		Type:      eventType,
+		Condition: payload.Condition,
+		Notes:     payload.Notes,
+		ActorID:   &claims.UserID,
+		CreatedAt: now,
+	})
+
+	h.writeUnitDetail(w, bson.M{"_id": unit.ID})
+}
+
+// GetUnitLabel renders the label of one unit as a PNG (format=qr, the
+// default, or code128).
+func (h *AssetUnitHandler) GetUnitLabel(w http.ResponseWriter, r *http.Request) {
+	unitID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Unit ID format", http.StatusBadRequest)
+		return
+	}
+	format, ok := labelFormat(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var unit models.AssetUnit
+	if err := h.db.Collection("asset_units").FindOne(ctx, bson.M{"_id": unitID}).Decode(&unit); err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Unit not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve unit", http.StatusInternalServerError)
+		return
+	}
+	var item models.InventoryItem
+	if err := h.db.Collection("inventory_items").FindOne(ctx, bson.M{"_id": unit.ItemID}).Decode(&item); err != nil {
+		http.Error(w, "Failed to retrieve item", http.StatusInternalServerError)
+		return
+	}
+
+	img, err := labels.Render(unitLabel(unit, item), format)
+	if err != nil {
+		log.Printf("ERROR: Failed to render label of unit %s: %v", unit.ID.Hex(), err)
+		http.Error(w, "Failed to render label", http.StatusInternalServerError)
+		return
+	}
+	writePNG(w, img, unit.AssetTag+".png")
+}
+
+// GetItemLabelSheet renders the labels of all units in service of an item
+// as one PNG sheet (format, columns).
+func (h *AssetUnitHandler) GetItemLabelSheet(w http.ResponseWriter, r *http.Request) {
+	itemID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Item ID format", http.StatusBadRequest)
+		return
+	}
+	format, ok := labelFormat(w, r)
+	if !ok {
+		return
+	}
+	columns := defaultLabelColumns
+	if value := r.URL.Query().Get("columns"); value != "" {
+		columns, err = strconv.Atoi(value)
+		if err != nil || columns < 1 || columns > maxLabelColumns {
+			http.Error(w, fmt.Sprintf("columns must be between 1 and %d", maxLabelColumns), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var item models.InventoryItem
+	err = h.db.Collection("inventory_items").FindOne(ctx, bson.M{"_id": itemID, "deleted_at": nil}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Item not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve item", http.StatusInternalServerError)
+		return
+	}
+	units, err := h.findUnits(ctx, bson.M{"item_id": itemID, "status": bson.M{"$ne": models.AssetUnitRetired}})
+	if err != nil {
+		http.Error(w, "Failed to retrieve units", http.StatusInternalServerError)
+		return
+	}
+	if len(units) == 0 {
+		http.Error(w, "Item has no tagged units", http.StatusNotFound)
+		return
+	}
+	if len(units) > maxLabelsPerSheet {
+		http.Error(w, fmt.Sprintf("Item has more than %d units; print their labels one by one", maxLabelsPerSheet), http.StatusBadRequest)
+		return
+	}
+
+	sheet := make([]labels.Label, len(units))
+	for i, unit := range units {
+		sheet[i] = unitLabel(unit, item)
+	}
+	img, err := labels.Sheet(sheet, format, columns)
+	if err != nil {
+		log.Printf("ERROR: Failed to render label sheet of item %s: %v", itemID.Hex(), err)
+		http.Error(w, "Failed to render labels", http.StatusInternalServerError)
+		return
+	}
+	writePNG(w, img, item.Code+"-labels.png")
+}
+
+func (h *AssetUnitHandler) findUnits(ctx context.Context, filter bson.M) ([]models.AssetUnit, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "asset_tag", Value: 1}})
+	cursor, err := h.db.Collection("asset_units").Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	units := []models.AssetUnit{}
+	if err := cursor.All(ctx, &units); err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+// labelFormat reads the format query parameter, defaulting to qr.
+func labelFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	switch format {
+	case "":
+		return labels.FormatQR, true
+	case labels.FormatQR, labels.FormatCode128:
+		return format, true
+	}
+	http.Error(w, "format must be qr or code128", http.StatusBadRequest)
+	return "", false
+}
+
+// unitLabel encodes the asset tag, which GetUnitByTag resolves when the
+// label is scanned.
+func unitLabel(unit models.AssetUnit, item models.InventoryItem) labels.Label {
+	caption := []string{unit.AssetTag, item.Name}
+	if unit.SerialNumber != "" {
+		caption = append(caption, "S/N "+unit.SerialNumber)
+	}
+	return labels.Label{Value: unit.AssetTag, Caption: caption}
+}
+
+func writePNG(w http.ResponseWriter, img *image.RGBA, filename string) {
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	if err := png.Encode(w, img); err != nil {
+		log.Printf("Failed to write %s: %v", filename, err)
+	}
+}
+
+var errUnitUnavailable = errors.New("is not available")
+
+// assignUnits marks the units as out on the loan. Each unit must belong to
+// the item, be available and not be damaged. Claiming each unit with a
+// conditional update means two pickups can never take the same unit; if
+// any unit cannot be claimed, the ones already claimed are released.
+func assignUnits(ctx context.Context, db *mongo.Database, loanID, itemID primitive.ObjectID, unitIDs []primitive.ObjectID, now time.Time) error {
+	collection := db.Collection("asset_units")
+	for i, unitID := range unitIDs {
+		result, err := collection.UpdateOne(ctx,
+			bson.M{
+				"_id":       unitID,
+				"item_id":   itemID,
+				"status":    models.AssetUnitAvailable,
+				"condition": bson.M{"$ne": models.ItemConditionDamaged},
+			},
+			bson.M{"$set": bson.M{"status": models.AssetUnitOnLoan, "current_loan_id": loanID, "updated_at": now}},
+		)
+		if err == nil && result.MatchedCount == 0 {
+			err = fmt.Errorf("unit %s %w", unitID.Hex(), errUnitUnavailable)
+		}
+		if err != nil {
+			if releaseErr := releaseUnits(context.Background(), db, loanID, unitIDs[:i], bson.M{}); releaseErr != nil {
+				log.Printf("ERROR: Failed to release units of loan %s: %v", loanID.Hex(), releaseErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseUnits makes the units out on the loan available again, applying
+// set as well.
+func releaseUnits(ctx context.Context, db *mongo.Database, loanID primitive.ObjectID, unitIDs []primitive.ObjectID, set bson.M) error {
+	if len(unitIDs) == 0 {
+		return nil
+	}
+	set["status"] = models.AssetUnitAvailable
+	set["updated_at"] = time.Now()
+	_, err := db.Collection("asset_units").UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": unitIDs}, "current_loan_id": loanID},
+		bson.M{"$set": set, "$unset": bson.M{"current_loan_id": ""}},
+	)
+	return err
+}
+
+// lendableQuantities returns, per item, how many units can be lent out at
+// all. For items with tagged units in service this is the units on loan plus
+// the available units that are not damaged, since a pickup must name such
+// units and assignUnits refuses the others; units in repair, retired or
+// damaged do not count. Other items can lend their whole TotalQuantity.
+func lendableQuantities(ctx context.Context, db *mongo.Database, items []models.InventoryItem) (map[primitive.ObjectID]int, error) {
+	ids := make([]primitive.ObjectID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	lendable := bson.M{"$or": bson.A{
+		bson.M{"$eq": bson.A{"$status", models.AssetUnitOnLoan}},
+		bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{"$status", models.AssetUnitAvailable}},
+			bson.M{"$ne": bson.A{"$condition", models.ItemConditionDamaged}},
+		}},
+	}}
+	cursor, err := db.Collection("asset_units").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"item_id": bson.M{"$in": ids}, "status": bson.M{"$ne": models.AssetUnitRetired}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$item_id",
+			"lendable": bson.M{"$sum": bson.M{"$cond": bson.A{lendable, 1, 0}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		ItemID   primitive.ObjectID `bson:"_id"`
+		Lendable int                `bson:"lendable"`
+	}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	quantities := make(map[primitive.ObjectID]int, len(items))
+	for _, item := range items {
+		quantities[item.ID] = item.TotalQuantity
+	}
+	for _, count := range counts {
+		quantities[count.ItemID] = min(quantities[count.ItemID], count.Lendable)
+	}
+	return quantities, nil
+}
+
+// lendableQuantity returns how many units of the item can be lent out at all.
+func lendableQuantity(ctx context.Context, db *mongo.Database, item models.InventoryItem) (int, error) {
+	quantities, err := lendableQuantities(ctx, db, []models.InventoryItem{item})
+	if err != nil {
+		return 0, err
+	}
+	return quantities[item.ID], nil
+}
+
+// trackedUnits counts the units of an item that are still in service.
+func trackedUnits(ctx context.Context, db *mongo.Database, itemID primitive.ObjectID) (int64, error) {
+	return db.Collection("asset_units").CountDocuments(ctx, bson.M{"item_id": itemID, "status": bson.M{"$ne": models.AssetUnitRetired}})
+}
+
+// recordUnitEvents appends entries to unit histories. Failures are logged,
+// since the change they describe has already been made.
+func recordUnitEvents(ctx context.Context, db *mongo.Database, events ...models.AssetUnitEvent) {
+	if len(events) == 0 {
+		return
+	}
+	docs := make([]interface{}, len(events))
+	for i, event := range events {
+		event.ID = primitive.NewObjectID()
+		docs[i] = event
+	}
+	if _, err := db.Collection("asset_unit_events").InsertMany(ctx, docs); err != nil {
+		log.Printf("ERROR: Failed to record asset unit history: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// insertUnits stores one unit of the item per status/condition pair.
+func insertUnits(t *testing.T, db *mongo.Database, itemID primitive.ObjectID, units ...[2]string) []primitive.ObjectID {
+	t.Helper()
+	ids := make([]primitive.ObjectID, len(units))
+	for i, unit := range units {
+		ids[i] = primitive.NewObjectID()
+		doc := models.AssetUnit{
+			ID:        ids[i],
+			ItemID:    itemID,
+			AssetTag:  fmt.Sprintf("LPT-%s-%d", itemID.Hex()[18:], i),
+			Status:    unit[0],
+			Condition: unit[1],
+			CreatedAt: time.Now(),
+		}
+		if _, err := db.Collection("asset_units").InsertOne(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+func unitStatus(t *testing.T, db *mongo.Database, id primitive.ObjectID) models.AssetUnit {
+	t.Helper()
+	var unit models.AssetUnit
+	if err := db.Collection("asset_units").FindOne(context.Background(), bson.M{"_id": id}).Decode(&unit); err != nil {
+		t.Fatal(err)
+	}
+	return unit
+}
+
+func TestAssignUnitsClaimsUnits(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	itemID, loanID := primitive.NewObjectID(), primitive.NewObjectID()
+	ids := insertUnits(t, db, itemID,
+		[2]string{models.AssetUnitAvailable, models.ItemConditionGood},
+		[2]string{models.AssetUnitAvailable, models.ItemConditionFair},
+	)
+
+	if err := assignUnits(ctx, db, loanID, itemID, ids, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		unit := unitStatus(t, db, id)
+		if unit.Status != models.AssetUnitOnLoan || unit.CurrentLoanID == nil || *unit.CurrentLoanID != loanID {
+			t.Errorf("unit %s: status %q on loan %v, want %q on %s", id.Hex(), unit.Status, unit.CurrentLoanID, models.AssetUnitOnLoan, loanID.Hex())
+		}
+	}
+
+	// A second pickup cannot take the same units.
+	if err := assignUnits(ctx, db, primitive.NewObjectID(), itemID, ids[:1], time.Now()); !errors.Is(err, errUnitUnavailable) {
+		t.Errorf("claiming a unit on loan: got %v, want %v", err, errUnitUnavailable)
+	}
+}
+
+func TestAssignUnitsRollsBackOnUnavailableUnit(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	itemID := primitive.NewObjectID()
+	otherItem := insertUnits(t, db, primitive.NewObjectID(), [2]string{models.AssetUnitAvailable, models.ItemConditionGood})[0]
+
+	tests := []struct {
+		name string
+		last func() primitive.ObjectID
+	}{
+		{"damaged", func() primitive.ObjectID {
+			return insertUnits(t, db, itemID, [2]string{models.AssetUnitAvailable, models.ItemConditionDamaged})[0]
+		}},
+		{"in repair", func() primitive.ObjectID {
+			return insertUnits(t, db, itemID, [2]string{models.AssetUnitInRepair, models.ItemConditionGood})[0]
+		}},
+		{"retired", func() primitive.ObjectID {
+			return insertUnits(t, db, itemID, [2]string{models.AssetUnitRetired, models.ItemConditionGood})[0]
+		}},
+		{"of another item", func() primitive.ObjectID { return otherItem }},
+		{"missing", primitive.NewObjectID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claimable := insertUnits(t, db, itemID,
+				[2]string{models.AssetUnitAvailable, models.ItemConditionGood},
+				[2]string{models.AssetUnitAvailable, models.ItemConditionGood},
+			)
+			ids := append(claimable, tt.last())
+
+			err := assignUnits(ctx, db, primitive.NewObjectID(), itemID, ids, time.Now())
+			if !errors.Is(err, errUnitUnavailable) {
+				t.Fatalf("got %v, want %v", err, errUnitUnavailable)
+			}
+			for _, id := range claimable {
+				if unit := unitStatus(t, db, id); unit.Status != models.AssetUnitAvailable || unit.CurrentLoanID != nil {
+					t.Errorf("unit %s was not released: status %q, loan %v", id.Hex(), unit.Status, unit.CurrentLoanID)
+				}
+			}
+		})
+	}
+}
+
+func TestLendableQuantities(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	tracked := models.InventoryItem{ID: primitive.NewObjectID(), TotalQuantity: 10}
+	insertUnits(t, db, tracked.ID,
+		[2]string{models.AssetUnitAvailable, models.ItemConditionGood},
+		[2]string{models.AssetUnitAvailable, models.ItemConditionFair},
+		[2]string{models.AssetUnitOnLoan, models.ItemConditionGood},
+		[2]string{models.AssetUnitAvailable, models.ItemConditionDamaged},
+		[2]string{models.AssetUnitInRepair, models.ItemConditionGood},
+		[2]string{models.AssetUnitRetired, models.ItemConditionGood},
+	)
+	// More tagged units than the stock: the stock still caps the quantity.
+	capped := models.InventoryItem{ID: primitive.NewObjectID(), TotalQuantity: 1}
+	insertUnits(t, db, capped.ID,
+		[2]string{models.AssetUnitAvailable, models.ItemConditionGood},
+		[2]string{models.AssetUnitAvailable, models.ItemConditionGood},
+	)
+	// Only retired units: nothing is in service, so the whole stock counts.
+	retired := models.InventoryItem{ID: primitive.NewObjectID(), TotalQuantity: 4}
+	insertUnits(t, db, retired.ID, [2]string{models.AssetUnitRetired, models.ItemConditionGood})
+	untracked := models.InventoryItem{ID: primitive.NewObjectID(), TotalQuantity: 5}
+
+	got, err := lendableQuantities(ctx, db, []models.InventoryItem{tracked, capped, retired, untracked})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[primitive.ObjectID]int{tracked.ID: 3, capped.ID: 1, retired.ID: 4, untracked.ID: 5}
+	for id, quantity := range want {
+		if got[id] != quantity {
+			t.Errorf("item %s: got %d, want %d", id.Hex(), got[id], quantity)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+)
+
+// openingHours are the department's opening hours per weekday, as offsets from
+// local midnight. Days without an entry are closed.
+var openingHours = map[time.Weekday][2]time.Duration{
+	time.Monday:    {7 * time.Hour, 17 * time.Hour},
+	time.Tuesday:   {7 * time.Hour, 17 * time.Hour},
+	time.Wednesday: {7 * time.Hour, 17 * time.Hour},
+	time.Thursday:  {7 * time.Hour, 17 * time.Hour},
+	time.Friday:    {7 * time.Hour, 17 * time.Hour},
+}
+
+// openIntervals returns the opening hours that fall within [from, to).
+func openIntervals(from, to time.Time) []models.TimeInterval {
+	var intervals []models.TimeInterval
+
+	local := from.In(department.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, department.Location)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		hours, open := openingHours[day.Weekday()]
+		if !open {
+			continue
+		}
+		start, end := day.Add(hours[0]), day.Add(hours[1])
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if start.Before(end) {
+			intervals = append(intervals, models.TimeInterval{Start: start, End: end})
+		}
+	}
+	return intervals
+}
+
+// mergeBusy sorts busy intervals and merges overlapping ones into plain time ranges.
+func mergeBusy(busy []models.BusyInterval) []models.TimeInterval {
+	sorted := make([]models.TimeInterval, 0, len(busy))
+	for _, b := range busy {
+		sorted = append(sorted, models.TimeInterval{Start: b.Start, End: b.End})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var merged []models.TimeInterval
+	for _, interval := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && !interval.Start.After(merged[last].End) {
+			if interval.End.After(merged[last].End) {
+				merged[last].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// subtractIntervals removes the (sorted, non-overlapping) busy ranges from the open ranges.
+func subtractIntervals(open, busy []models.TimeInterval) []models.TimeInterval {
+	free := []models.TimeInterval{}
+	for _, o := range open {
+		start := o.Start
+		for _, b := range busy {
+			if !b.End.After(start) || !b.Start.Before(o.End) {
+				continue
+			}
+			if b.Start.After(start) {
+				free = append(free, models.TimeInterval{Start: start, End: b.Start})
+			}
+			if b.End.After(start) {
+				start = b.End
+			}
+		}
+		if start.Before(o.End) {
+			free = append(free, models.TimeInterval{Start: start, End: o.End})
+		}
+	}
+	return free
+}
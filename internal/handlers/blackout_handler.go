@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/recurrence"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BlackoutHandler handles admin management of blackout periods.
+type BlackoutHandler struct {
+	db *mongo.Database
+}
+
+// NewBlackoutHandler creates a new BlackoutHandler.
+func NewBlackoutHandler(db *mongo.Database) *BlackoutHandler {
+	return &BlackoutHandler{db: db}
+}
+
+// GetBlackouts lists blackouts, optionally only those that may affect [from, to).
+func (h *BlackoutHandler) GetBlackouts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := bson.M{}
+	if value := query.Get("from"); value != "" {
+		from, err := parseTimeParam(value)
+		if err != nil {
+			http.Error(w, "Invalid 'from' format", http.StatusBadRequest)
+			return
+		}
+		// Recurring blackouts may have later occurrences, so only one-off ones are filtered by end.
+		filter["$or"] = []bson.M{
+			{"end_time": bson.M{"$gt": from}},
+			{"recurrence": bson.M{"$nin": []interface{}{nil, ""}}},
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		to, err := parseTimeParam(value)
+		if err != nil {
+			http.Error(w, "Invalid 'to' format", http.StatusBadRequest)
+			return
+		}
+		filter["start_time"] = bson.M{"$lt": to}
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "start_time", Value: -1}})
+
+	cursor, err := h.db.Collection("blackouts").Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve blackouts", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var blackouts []models.Blackout
+	if err = cursor.All(context.TODO(), &blackouts); err != nil {
+		http.Error(w, "Failed to parse blackouts data", http.StatusInternalServerError)
+		return
+	}
+
+	if blackouts == nil {
+		blackouts = []models.Blackout{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blackouts)
+}
+
+// CreateBlackout adds a new blackout period.
+func (h *BlackoutHandler) CreateBlackout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	blackout, ok := h.decodeBlackout(w, r)
+	if !ok {
+		return
+	}
+	blackout.ID = primitive.NewObjectID()
+	blackout.CreatedBy = claims.UserID
+	blackout.CreatedAt = time.Now()
+
+	if _, err := h.db.Collection("blackouts").InsertOne(context.TODO(), blackout); err != nil {
+		log.Printf("ERROR: Failed to insert blackout: %v", err)
+		http.Error(w, "Failed to create blackout", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(blackout)
+}
+
+// UpdateBlackout replaces the definition of an existing blackout.
+func (h *BlackoutHandler) UpdateBlackout(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Blackout ID format", http.StatusBadRequest)
+		return
+	}
+
+	blackout, ok := h.decodeBlackout(w, r)
+	if !ok {
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"title":      blackout.Title,
+			"reason":     blackout.Reason,
+			"scope":      blackout.Scope,
+			"building":   blackout.Building,
+			"room_id":    blackout.RoomID,
+			"start_time": blackout.StartTime,
+			"end_time":   blackout.EndTime,
+			"recurrence": blackout.Recurrence,
+		},
+	}
+	findOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var saved models.Blackout
+	err = h.db.Collection("blackouts").FindOneAndUpdate(context.TODO(), bson.M{"_id": objID}, update, findOptions).Decode(&saved)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Blackout not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("ERROR: Failed to update blackout %s: %v", objID.Hex(), err)
+		http.Error(w, "Failed to update blackout", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteBlackout removes a blackout period.
+func (h *BlackoutHandler) DeleteBlackout(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Blackout ID format", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Collection("blackouts").DeleteOne(context.TODO(), bson.M{"_id": objID})
+	if err != nil {
+		http.Error(w, "Failed to delete blackout", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Blackout not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Blackout deleted"})
+}
+
+// decodeBlackout parses and validates a BlackoutPayload, writing an error
+// response and returning false if it is invalid.
+func (h *BlackoutHandler) decodeBlackout(w http.ResponseWriter, r *http.Request) (models.Blackout, bool) {
+	var payload models.BlackoutPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return models.Blackout{}, false
+	}
+
+	blackout := models.Blackout{
+		Title:      strings.TrimSpace(payload.Title),
+		Reason:     strings.TrimSpace(payload.Reason),
+		Scope:      payload.Scope,
+		Recurrence: strings.TrimSpace(payload.Recurrence),
+	}
+	if blackout.Title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return blackout, false
+	}
+
+	switch payload.Scope {
+	case models.BlackoutGlobal:
+	case models.BlackoutBuilding:
+		blackout.Building = strings.TrimSpace(payload.Building)
+		if blackout.Building == "" {
+			http.Error(w, "Building is required for building blackouts", http.StatusBadRequest)
+			return blackout, false
+		}
+	case models.BlackoutRoom:
+		roomID, err := primitive.ObjectIDFromHex(payload.RoomID)
+		if err != nil {
+			http.Error(w, "Invalid Room ID format", http.StatusBadRequest)
+			return blackout, false
+		}
+		count, err := h.db.Collection("rooms").CountDocuments(context.TODO(), bson.M{"_id": roomID, "deleted_at": nil})
+		if err != nil {
+			http.Error(w, "Failed to retrieve room data", http.StatusInternalServerError)
+			return blackout, false
+		}
+		if count == 0 {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return blackout, false
+		}
+		blackout.RoomID = &roomID
+	default:
+		http.Error(w, "Scope must be one of global, building or room", http.StatusBadRequest)
+		return blackout, false
+	}
+
+	var err error
+	if blackout.StartTime, err = time.Parse(time.RFC3339, payload.StartTime); err != nil {
+		http.Error(w, "Invalid Start Time format", http.StatusBadRequest)
+		return blackout, false
+	}
+	if blackout.EndTime, err = time.Parse(time.RFC3339, payload.EndTime); err != nil {
+		http.Error(w, "Invalid End Time format", http.StatusBadRequest)
+		return blackout, false
+	}
+	if !blackout.EndTime.After(blackout.StartTime) {
+		http.Error(w, "End time must be after start time", http.StatusBadRequest)
+		return blackout, false
+	}
+	if blackout.Recurrence != "" {
+		if _, err := recurrence.Parse(blackout.Recurrence, department.Location); err != nil {
+			http.Error(w, "Invalid recurrence rule: "+err.Error(), http.StatusBadRequest)
+			return blackout, false
+		}
+	}
+
+	return blackout, true
+}
+
+// findBlackouts returns every blackout occurrence affecting the room within [from, to).
+func findBlackouts(ctx context.Context, db *mongo.Database, room models.Room, from, to time.Time) ([]models.BusyInterval, error) {
+	filter := bson.M{
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"scope": models.BlackoutGlobal},
+				{"scope": models.BlackoutBuilding, "building": room.Building()},
+				{"scope": models.BlackoutRoom, "room_id": room.ID},
+			}},
+			{"start_time": bson.M{"$lt": to}},
+			{"$or": []bson.M{
+				{"end_time": bson.M{"$gt": from}},
+				{"recurrence": bson.M{"$nin": []interface{}{nil, ""}}},
+			}},
+		},
+	}
+
+	cursor, err := db.Collection("blackouts").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var blackouts []models.Blackout
+	if err = cursor.All(ctx, &blackouts); err != nil {
+		return nil, err
+	}
+
+	var busy []models.BusyInterval
+	for _, blackout := range blackouts {
+		id := blackout.ID
+		duration := blackout.EndTime.Sub(blackout.StartTime)
+
+		starts := []time.Time{blackout.StartTime}
+		if blackout.Recurrence != "" {
+			rule, err := recurrence.Parse(blackout.Recurrence, department.Location)
+			if err != nil {
+				log.Printf("Skipping blackout %s with invalid recurrence: %v", id.Hex(), err)
+				continue
+			}
+			starts = rule.Overlapping(blackout.StartTime.In(department.Location), duration, from, to)
+		}
+
+		for _, start := range starts {
+			busy = append(busy, models.BusyInterval{
+				Start:      start,
+				End:        start.Add(duration),
+				Kind:       models.BusyBlackout,
+				BlackoutID: &id,
+				Title:      blackout.Title,
+			})
+		}
+	}
+	return busy, nil
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/policy"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	policyRoomTypes = []string{"high", "medium", "low", models.PolicyWildcard}
+	policyRoles     = []string{auth.RoleStudent, auth.RoleAdmin, auth.RoleSuperAdmin, models.PolicyWildcard}
+)
+
+// BookingPolicyHandler handles reading and configuring booking policies.
+type BookingPolicyHandler struct {
+	db *mongo.Database
+}
+
+// NewBookingPolicyHandler creates a new BookingPolicyHandler.
+func NewBookingPolicyHandler(db *mongo.Database) *BookingPolicyHandler {
+	return &BookingPolicyHandler{db: db}
+}
+
+// GetPolicies returns all booking policies so the frontend can show the rules up front.
+func (h *BookingPolicyHandler) GetPolicies(w http.ResponseWriter, r *http.Request) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "room_type", Value: 1}, {Key: "role", Value: 1}})
+
+	cursor, err := h.db.Collection("booking_policies").Find(context.TODO(), bson.M{}, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve booking policies", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var policies []models.BookingPolicy
+	if err = cursor.All(context.TODO(), &policies); err != nil {
+		http.Error(w, "Failed to parse booking policies", http.StatusInternalServerError)
+		return
+	}
+
+	if policies == nil {
+		policies = []models.BookingPolicy{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+// PutPolicy creates or replaces the policy for a room type and role pair.
+// allowBooking defaults to true when omitted.
+func (h *BookingPolicyHandler) PutPolicy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	payload := models.BookingPolicy{AllowBooking: true}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// --- Validation ---
+	if !slices.Contains(policyRoomTypes, payload.RoomType) {
+		http.Error(w, "roomType must be one of high, medium, low or *", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(policyRoles, payload.Role) {
+		http.Error(w, "role must be one of student, admin, superadmin or *", http.StatusBadRequest)
+		return
+	}
+	if payload.MaxDurationMinutes < 0 || payload.MinLeadMinutes < 0 || payload.MaxAdvanceDays < 0 || payload.MaxActiveReservations < 0 {
+		http.Error(w, "Limits cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if payload.AllowedFrom != "" || payload.AllowedUntil != "" {
+		from, fromErr := policy.ParseClock(payload.AllowedFrom)
+		until, untilErr := policy.ParseClock(payload.AllowedUntil)
+		if fromErr != nil || untilErr != nil {
+			http.Error(w, "allowedFrom and allowedUntil must both be given as HH:MM", http.StatusBadRequest)
+			return
+		}
+		if until <= from {
+			http.Error(w, "allowedUntil must be after allowedFrom", http.StatusBadRequest)
+			return
+		}
+	}
+
+	payload.UpdatedAt = time.Now()
+	payload.UpdatedBy = &claims.UserID
+
+	filter := bson.M{"room_type": payload.RoomType, "role": payload.Role}
+	update := bson.M{
+		"$set": bson.M{
+			"allow_booking":           payload.AllowBooking,
+			"max_duration_minutes":    payload.MaxDurationMinutes,
+			"min_lead_minutes":        payload.MinLeadMinutes,
+			"max_advance_days":        payload.MaxAdvanceDays,
+			"allowed_from":            payload.AllowedFrom,
+			"allowed_until":           payload.AllowedUntil,
+			"max_active_reservations": payload.MaxActiveReservations,
+			"updated_at":              payload.UpdatedAt,
+			"updated_by":              payload.UpdatedBy,
+		},
+		"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+	}
+	findOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved models.BookingPolicy
+	err := h.db.Collection("booking_policies").FindOneAndUpdate(context.TODO(), filter, update, findOptions).Decode(&saved)
+	if err != nil {
+		log.Printf("ERROR: Failed to save booking policy: %v", err)
+		http.Error(w, "Failed to save booking policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeletePolicy removes a booking policy, falling back to less specific ones.
+func (h *BookingPolicyHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Policy ID format", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Collection("booking_policies").DeleteOne(context.TODO(), bson.M{"_id": objID})
+	if err != nil {
+		http.Error(w, "Failed to delete booking policy", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Booking policy not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Booking policy deleted"})
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CatalogHandler handles requests for catalog data, including search.
@@ -39,7 +43,7 @@ func (h *CatalogHandler) GetRoomByID(w http.ResponseWriter, r *http.Request) {
 	var room models.Room
 
 	// Find the room where the '_id' field matches the ObjectID
-	err = collection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&room)
+	err = collection.FindOne(context.TODO(), bson.M{"_id": objID, "deleted_at": nil}).Decode(&room)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			http.Error(w, "Room not found", http.StatusNotFound)
@@ -49,41 +53,71 @@ func (h *CatalogHandler) GetRoomByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rooms := []models.Room{room}
+	if err := deriveRoomStatuses(context.TODO(), h.db, rooms); err != nil {
+		http.Error(w, "Failed to determine room status", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(room)
+	json.NewEncoder(w).Encode(rooms[0])
 }
 
-// SearchCatalog fetches rooms based on search query and status filters.
+// GetItemByID fetches a single inventory item with its available quantity.
+func (h *CatalogHandler) GetItemByID(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Item ID format", http.StatusBadRequest)
+		return
+	}
+
+	var item models.InventoryItem
+	err = h.db.Collection("inventory_items").FindOne(context.TODO(), bson.M{"_id": objID, "deleted_at": nil}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Item not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve item data", http.StatusInternalServerError)
+		return
+	}
+
+	items := []models.InventoryItem{item}
+	if err := deriveAvailableQuantities(context.TODO(), h.db, items); err != nil {
+		http.Error(w, "Failed to determine item availability", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items[0])
+}
+
+// SearchCatalog fetches rooms (type=ruangan, the default) or inventory items
+// (type=barang) based on search query and status filters.
 func (h *CatalogHandler) SearchCatalog(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	searchQuery := strings.TrimSpace(query.Get("q"))
 	statusFilter := query.Get("status")
 	typeFilter := query.Get("type")
 
-	// For now, we only support searching for "ruangan".
-	if typeFilter != "" && typeFilter != "ruangan" {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode([]models.Room{}) // Return empty for non-room searches
+	switch typeFilter {
+	case "", "ruangan":
+	case "barang":
+		h.searchItems(w, searchQuery, query.Get("category"), statusFilter)
+		return
+	default:
+		http.Error(w, "type must be ruangan or barang", http.StatusBadRequest)
 		return
 	}
 
 	collection := h.db.Collection("rooms")
-	filter := bson.M{}
+	filter := bson.M{"deleted_at": nil}
 
 	// Add search query to filter (case-insensitive regex search on the 'name' field)
 	if searchQuery != "" {
 		filter["name"] = bson.M{"$regex": searchQuery, "$options": "i"}
 	}
 
-	// Add status filter if provided
-	if statusFilter != "" {
-		if statusFilter == "tersedia" {
-			filter["status"] = "Available"
-		} else if statusFilter == "tidak tersedia" {
-			filter["status"] = bson.M{"$in": []string{"In Use", "Under Maintenance"}}
-		}
-	}
-
 	cursor, err := collection.Find(context.TODO(), filter)
 	if err != nil {
 		http.Error(w, "Failed to execute search", http.StatusInternalServerError)
@@ -97,6 +131,22 @@ func (h *CatalogHandler) SearchCatalog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Status is derived live, so the status filter is applied after derivation.
+	if err = deriveRoomStatuses(context.TODO(), h.db, rooms); err != nil {
+		http.Error(w, "Failed to determine room status", http.StatusInternalServerError)
+		return
+	}
+	if statusFilter == "tersedia" || statusFilter == "tidak tersedia" {
+		wantAvailable := statusFilter == "tersedia"
+		filtered := []models.Room{}
+		for _, room := range rooms {
+			if (room.Status == models.RoomAvailable) == wantAvailable {
+				filtered = append(filtered, room)
+			}
+		}
+		rooms = filtered
+	}
+
 	if rooms == nil {
 		rooms = []models.Room{}
 	}
@@ -104,3 +154,172 @@ func (h *CatalogHandler) SearchCatalog(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(rooms)
 }
+
+// searchItems answers a catalog search for inventory items. q matches the
+// name, code or category; status=tersedia keeps items with units available.
+func (h *CatalogHandler) searchItems(w http.ResponseWriter, searchQuery, category, statusFilter string) {
+	filter := bson.M{"deleted_at": nil}
+	if searchQuery != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(searchQuery), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": pattern},
+			bson.M{"code": pattern},
+			bson.M{"category": pattern},
+		}
+	}
+	if category = strings.TrimSpace(category); category != "" {
+		filter["category"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(category) + "$", Options: "i"}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := h.db.Collection("inventory_items").Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to execute search", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	items := []models.InventoryItem{}
+	if err = cursor.All(context.TODO(), &items); err != nil {
+		http.Error(w, "Failed to parse items data", http.StatusInternalServerError)
+		return
+	}
+
+	// Availability is derived live, so the status filter is applied after derivation.
+	if err = deriveAvailableQuantities(context.TODO(), h.db, items); err != nil {
+		http.Error(w, "Failed to determine item availability", http.StatusInternalServerError)
+		return
+	}
+	if statusFilter == "tersedia" || statusFilter == "tidak tersedia" {
+		wantAvailable := statusFilter == "tersedia"
+		filtered := []models.InventoryItem{}
+		for _, item := range items {
+			if (item.AvailableQuantity > 0) == wantAvailable {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// maxAvailabilityRange caps how far apart from and to may be in an availability query.
+const maxAvailabilityRange = 62 * 24 * time.Hour
+
+// GetRoomAvailability returns the busy and free intervals of a room between
+// from and to (RFC3339 or YYYY-MM-DD, defaulting to the next 7 days). Approved
+// reservations, maintenance windows and blackouts are always busy; pending reservations are included
+// with includePending=true.
+func (h *CatalogHandler) GetRoomAvailability(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Room ID format", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now().In(department.Location)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, department.Location)
+	if value := query.Get("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			http.Error(w, "Invalid 'from' format", http.StatusBadRequest)
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 7)
+	if value := query.Get("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			http.Error(w, "Invalid 'to' format", http.StatusBadRequest)
+			return
+		}
+	}
+	if !to.After(from) {
+		http.Error(w, "'to' must be after 'from'", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) > maxAvailabilityRange {
+		http.Error(w, "The requested range is too long", http.StatusBadRequest)
+		return
+	}
+	includePending := query.Get("includePending") == "true"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var room models.Room
+	err = h.db.Collection("rooms").FindOne(ctx, bson.M{"_id": objID, "deleted_at": nil}).Decode(&room)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve room data", http.StatusInternalServerError)
+		return
+	}
+
+	busy := []models.BusyInterval{}
+
+	maintenance, err := findMaintenance(ctx, h.db, room.ID, from, to)
+	if err != nil {
+		http.Error(w, "Failed to retrieve maintenance windows", http.StatusInternalServerError)
+		return
+	}
+	busy = append(busy, maintenance...)
+
+	blackouts, err := findBlackouts(ctx, h.db, room, from, to)
+	if err != nil {
+		http.Error(w, "Failed to retrieve blackouts", http.StatusInternalServerError)
+		return
+	}
+	busy = append(busy, blackouts...)
+
+	statuses := []string{models.ReservationApproved}
+	if includePending {
+		statuses = append(statuses, models.ReservationPending)
+	}
+	filter := overlapFilter(objID, from, to)
+	filter["status"] = bson.M{"$in": statuses}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "start_time", Value: 1}})
+
+	cursor, err := h.db.Collection("reservations").Find(ctx, filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var reservations []models.Reservation
+	if err = cursor.All(ctx, &reservations); err != nil {
+		http.Error(w, "Failed to parse reservations data", http.StatusInternalServerError)
+		return
+	}
+
+	for _, reservation := range reservations {
+		kind := models.BusyReservation
+		if reservation.Status == models.ReservationPending {
+			kind = models.BusyPending
+		}
+		id := reservation.ID
+		busy = append(busy, models.BusyInterval{
+			Start:         reservation.StartTime,
+			End:           reservation.EndTime,
+			Kind:          kind,
+			ReservationID: &id,
+		})
+	}
+
+	availability := models.RoomAvailability{
+		RoomID: objID,
+		From:   from,
+		To:     to,
+		Busy:   busy,
+		Free:   subtractIntervals(openIntervals(from, to), mergeBusy(busy)),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(availability)
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxItemQuantity = 10000
+
+var (
+	itemCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,30}$`)
+	itemConditions  = []string{models.ItemConditionGood, models.ItemConditionFair, models.ItemConditionDamaged}
+)
+
+// InventoryHandler handles admin management of inventory items.
+type InventoryHandler struct {
+	db *mongo.Database
+}
+
+// NewInventoryHandler creates a new InventoryHandler.
+func NewInventoryHandler(db *mongo.Database) *InventoryHandler {
+	return &InventoryHandler{db: db}
+}
+
+// CreateItem adds a new item to the inventory catalog.
+func (h *InventoryHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := decodeItem(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	item.ID = primitive.NewObjectID()
+	item.CreatedAt = now
+	item.UpdatedAt = now
+
+	_, err := h.db.Collection("inventory_items").InsertOne(context.TODO(), item)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Item code already in use", http.StatusConflict)
+			return
+		}
+		log.Printf("ERROR: Failed to insert inventory item: %v", err)
+		http.Error(w, "Failed to create item", http.StatusInternalServerError)
+		return
+	}
+	item.AvailableQuantity = item.TotalQuantity
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+// UpdateItem replaces the editable fields of an item. The total quantity
+// cannot drop below the units committed to current and upcoming loans, nor
+// below the number of tagged units in service.
+func (h *InventoryHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Item ID format", http.StatusBadRequest)
+		return
+	}
+
+	item, ok := decodeItem(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Hold the item lock so that no loan can be approved between the check
+	// and the update below.
+	lock, err := lockItem(ctx, h.db, objID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock item %s: %v", objID.Hex(), err)
+		http.Error(w, "The item is busy, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	defer lock.Release(context.Background())
+
+	committed, err := loanUsage(ctx, h.db, objID, time.Now(), time.Time{}, primitive.NilObjectID)
+	if err != nil {
+		http.Error(w, "Failed to determine item availability", http.StatusInternalServerError)
+		return
+	}
+	if item.TotalQuantity < committed {
+		http.Error(w, fmt.Sprintf("totalQuantity cannot be below the %d unit(s) committed to approved loans", committed), http.StatusConflict)
+		return
+	}
+	tracked, err := trackedUnits(ctx, h.db, objID)
+	if err != nil {
+		http.Error(w, "Failed to count item units", http.StatusInternalServerError)
+		return
+	}
+	if int64(item.TotalQuantity) < tracked {
+		http.Error(w, fmt.Sprintf("totalQuantity cannot be below the %d tagged unit(s) in service; retire units first", tracked), http.StatusConflict)
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"code":           item.Code,
+		"name":           item.Name,
+		"category":       item.Category,
+		"description":    item.Description,
+		"total_quantity": item.TotalQuantity,
+		"location":       item.Location,
+		"condition":      item.Condition,
+		"image_url":      item.ImageURL,
+		"updated_at":     time.Now(),
+	}}
+	findOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var saved models.InventoryItem
+	err = h.db.Collection("inventory_items").FindOneAndUpdate(ctx, bson.M{"_id": objID, "deleted_at": nil}, update, findOptions).Decode(&saved)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Item not found", http.StatusNotFound)
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Item code already in use", http.StatusConflict)
+			return
+		}
+		log.Printf("ERROR: Failed to update inventory item %s: %v", objID.Hex(), err)
+		http.Error(w, "Failed to update item", http.StatusInternalServerError)
+		return
+	}
+	items := []models.InventoryItem{saved}
+	if err := deriveAvailableQuantities(ctx, h.db, items); err != nil {
+		log.Printf("ERROR: Failed to derive availability of item %s: %v", objID.Hex(), err)
+	}
+	saved = items[0]
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteItem soft-deletes an item. Items with active or upcoming loans or
+// with tagged units in service cannot be deleted.
+func (h *InventoryHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Item ID format", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lock, err := lockItem(ctx, h.db, objID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock item %s: %v", objID.Hex(), err)
+		http.Error(w, "The item is busy, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	defer lock.Release(context.Background())
+
+	committed, err := loanUsage(ctx, h.db, objID, time.Now(), time.Time{}, primitive.NilObjectID)
+	if err != nil {
+		http.Error(w, "Failed to determine item availability", http.StatusInternalServerError)
+		return
+	}
+	if committed > 0 {
+		http.Error(w, "Item has active or upcoming loans and cannot be deleted", http.StatusConflict)
+		return
+	}
+	tracked, err := trackedUnits(ctx, h.db, objID)
+	if err != nil {
+		http.Error(w, "Failed to count item units", http.StatusInternalServerError)
+		return
+	}
+	if tracked > 0 {
+		http.Error(w, "Item has tagged units in service; retire them before deleting the item", http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	result, err := h.db.Collection("inventory_items").UpdateOne(ctx,
+		bson.M{"_id": objID, "deleted_at": nil},
+		bson.M{"$set": bson.M{"deleted_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to delete item", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Item deleted successfully"})
+}
+
+// decodeItem reads and validates an item payload.
+func decodeItem(w http.ResponseWriter, r *http.Request) (models.InventoryItem, bool) {
+	var payload models.InventoryItemPayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return models.InventoryItem{}, false
+	}
+
+	item := models.InventoryItem{
+		Code:          strings.ToUpper(strings.TrimSpace(payload.Code)),
+		Name:          strings.TrimSpace(payload.Name),
+		Category:      strings.TrimSpace(payload.Category),
+		Description:   strings.TrimSpace(payload.Description),
+		TotalQuantity: payload.TotalQuantity,
+		Location:      strings.TrimSpace(payload.Location),
+		Condition:     strings.ToLower(strings.TrimSpace(payload.Condition)),
+		ImageURL:      strings.TrimSpace(payload.ImageURL),
+	}
+	if item.Condition == "" {
+		item.Condition = models.ItemConditionGood
+	}
+
+	switch {
+	case !itemCodePattern.MatchString(item.Code):
+		http.Error(w, "code must be 1-30 letters, digits, '-' or '_'", http.StatusBadRequest)
+	case item.Name == "":
+		http.Error(w, "Name is required", http.StatusBadRequest)
+	case item.Category == "":
+		http.Error(w, "Category is required", http.StatusBadRequest)
+	case item.Location == "":
+		http.Error(w, "Location is required", http.StatusBadRequest)
+	case item.TotalQuantity < 0 || item.TotalQuantity > maxItemQuantity:
+		http.Error(w, fmt.Sprintf("totalQuantity must be between 0 and %d", maxItemQuantity), http.StatusBadRequest)
+	case !slices.Contains(itemConditions, item.Condition):
+		http.Error(w, "condition must be one of good, fair or damaged", http.StatusBadRequest)
+	default:
+		return item, true
+	}
+	return item, false
+}
+
+// heldQuantities returns, per item, how many units holding loans have out
+// right now.
+func heldQuantities(ctx context.Context, db *mongo.Database, itemIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	loans, err := holdingLoans(ctx, db, bson.M{"item_id": bson.M{"$in": itemIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	held := make(map[primitive.ObjectID]int)
+	for _, loan := range loans {
+		start, end, open := loanPeriod(loan, now)
+		if !now.Before(start) && (open || now.Before(end)) {
+			held[*loan.ItemID] += loan.Quantity
+		}
+	}
+	return held, nil
+}
+
+// deriveAvailableQuantities sets each item's AvailableQuantity from the
+// units it can lend and the loans holding its units right now.
+func deriveAvailableQuantities(ctx context.Context, db *mongo.Database, items []models.InventoryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	held, err := heldQuantities(ctx, db, ids)
+	if err != nil {
+		return err
+	}
+	lendable, err := lendableQuantities(ctx, db, items)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		items[i].AvailableQuantity = max(lendable[items[i].ID]-held[items[i].ID], 0)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+)
+
+// JWKSHandler serves the public keys access tokens are signed with.
+type JWKSHandler struct {
+	tokens *auth.Tokens
+}
+
+// NewJWKSHandler creates a new JWKSHandler.
+func NewJWKSHandler(tokens *auth.Tokens) *JWKSHandler {
+	return &JWKSHandler{tokens: tokens}
+}
+
+// GetJWKS serves the public keys access tokens are signed with, so other
+// campus services can verify our tokens.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	set := h.tokens.JWKS()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(set)
+}
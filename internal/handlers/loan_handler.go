@@ -0,0 +1,732 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/database"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxLoanTextLength = 500
+
+// anonymousRequesterName is shown for requesters who have not set a name.
+const anonymousRequesterName = "Pengguna"
+
+// loanTransitions lists, for each target status, the statuses a loan must
+// currently be in for an admin to move it there.
+var loanTransitions = map[string][]string{
+	models.InventoryRequestApproved: {models.InventoryRequestPending},
+	models.InventoryRequestRejected: {models.InventoryRequestPending},
+	models.InventoryRequestPickedUp: {models.InventoryRequestApproved},
+	models.InventoryRequestReturned: {models.InventoryRequestPickedUp, models.InventoryRequestOverdue},
+}
+
+// LoanHandler handles requests to borrow inventory items.
+type LoanHandler struct {
+	db *mongo.Database
+}
+
+// NewLoanHandler creates a new LoanHandler.
+func NewLoanHandler(db *mongo.Database) *LoanHandler {
+	return &LoanHandler{db: db}
+}
+
+// CreateLoan submits a Pending request to borrow units of an item.
+func (h *LoanHandler) CreateLoan(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.CreateLoanPayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// --- Validation ---
+	itemID, err := primitive.ObjectIDFromHex(payload.ItemID)
+	if err != nil {
+		http.Error(w, "Invalid Item ID format", http.StatusBadRequest)
+		return
+	}
+	neededFrom, err := time.Parse(time.RFC3339, payload.NeededFrom)
+	if err != nil {
+		http.Error(w, "Invalid neededFrom format", http.StatusBadRequest)
+		return
+	}
+	neededUntil, err := time.Parse(time.RFC3339, payload.NeededUntil)
+	if err != nil {
+		http.Error(w, "Invalid neededUntil format", http.StatusBadRequest)
+		return
+	}
+	payload.Purpose = strings.TrimSpace(payload.Purpose)
+
+	switch {
+	case payload.Quantity < 1 || payload.Quantity > maxItemQuantity:
+		http.Error(w, fmt.Sprintf("quantity must be between 1 and %d", maxItemQuantity), http.StatusBadRequest)
+		return
+	case !neededUntil.After(neededFrom):
+		http.Error(w, "neededUntil must be after neededFrom", http.StatusBadRequest)
+		return
+	case !neededUntil.After(time.Now()):
+		http.Error(w, "neededUntil must be in the future", http.StatusBadRequest)
+		return
+	case payload.Purpose == "":
+		http.Error(w, "Purpose is required", http.StatusBadRequest)
+		return
+	case utf8.RuneCountInString(payload.Purpose) > maxLoanTextLength:
+		http.Error(w, fmt.Sprintf("purpose must be at most %d characters", maxLoanTextLength), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item, ok := h.findItem(ctx, w, itemID)
+	if !ok {
+		return
+	}
+
+	// Reject requests that could never be approved right away. Pending loans
+	// hold nothing, so no lock is needed here; approval checks again under
+	// the item lock.
+	used, err := loanUsage(ctx, h.db, itemID, neededFrom, neededUntil, primitive.NilObjectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to compute usage of item %s: %v", itemID.Hex(), err)
+		http.Error(w, "Failed to determine item availability", http.StatusInternalServerError)
+		return
+	}
+	lendable, err := lendableQuantity(ctx, h.db, item)
+	if err != nil {
+		log.Printf("ERROR: Failed to count lendable units of item %s: %v", itemID.Hex(), err)
+		http.Error(w, "Failed to determine item availability", http.StatusInternalServerError)
+		return
+	}
+	if available := lendable - used; payload.Quantity > available {
+		http.Error(w, fmt.Sprintf("Only %d unit(s) available for the requested period", max(available, 0)), http.StatusConflict)
+		return
+	}
+
+	var user models.User
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": claims.UserID}).Decode(&user); err != nil {
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return
+	}
+	// The requester name is shown on the public status page, so fall back
+	// to a generic name rather than the email address.
+	requesterName := user.FullName
+	if requesterName == "" {
+		requesterName = anonymousRequesterName
+	}
+
+	// --- Create Loan ---
+	now := time.Now()
+	id := primitive.NewObjectID()
+	loan := models.InventoryRequest{
+		ID:            id,
+		RequestID:     "REQ-" + strings.ToUpper(id.Hex()),
+		RequesterName: requesterName,
+		ItemName:      item.Name,
+		RequestDate:   now,
+		Status:        models.InventoryRequestPending,
+		PickupDate:    neededFrom,
+		ItemID:        &itemID,
+		Quantity:      payload.Quantity,
+		UserID:        &claims.UserID,
+		Purpose:       payload.Purpose,
+		NeededFrom:    &neededFrom,
+		NeededUntil:   &neededUntil,
+	}
+
+	if _, err := h.db.Collection("inventory_requests").InsertOne(ctx, loan); err != nil {
+		log.Printf("ERROR: Failed to insert loan request: %v", err)
+		http.Error(w, "Failed to create loan request", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(loan)
+}
+
+// ListMyLoans returns the caller's loan requests, newest first, optionally
+// filtered by status.
+func (h *LoanHandler) ListMyLoans(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	filter := bson.M{"user_id": claims.UserID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+	h.writeLoans(w, filter)
+}
+
+// ListLoans returns all loan requests for admins, optionally filtered by
+// status and item.
+func (h *LoanHandler) ListLoans(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := bson.M{}
+	if status := query.Get("status"); status != "" {
+		filter["status"] = status
+	}
+	if itemID := query.Get("itemId"); itemID != "" {
+		objID, err := primitive.ObjectIDFromHex(itemID)
+		if err != nil {
+			http.Error(w, "Invalid Item ID format", http.StatusBadRequest)
+			return
+		}
+		filter["item_id"] = objID
+	}
+	h.writeLoans(w, filter)
+}
+
+func (h *LoanHandler) writeLoans(w http.ResponseWriter, filter bson.M) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "request_date", Value: -1}})
+
+	cursor, err := h.db.Collection("inventory_requests").Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve loan requests", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var loans []models.InventoryRequest
+	if err = cursor.All(context.TODO(), &loans); err != nil {
+		http.Error(w, "Failed to parse loan requests data", http.StatusInternalServerError)
+		return
+	}
+
+	if loans == nil {
+		loans = []models.InventoryRequest{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loans)
+}
+
+// ApproveLoan approves a pending loan if enough units are free for its
+// whole period.
+func (h *LoanHandler) ApproveLoan(w http.ResponseWriter, r *http.Request) {
+	h.decideLoan(w, r, models.InventoryRequestApproved)
+}
+
+// RejectLoan rejects a pending loan. A reason is required.
+func (h *LoanHandler) RejectLoan(w http.ResponseWriter, r *http.Request) {
+	h.decideLoan(w, r, models.InventoryRequestRejected)
+}
+
+// decideLoan approves or rejects a loan, recording who made the decision,
+// when and why.
+func (h *LoanHandler) decideLoan(w http.ResponseWriter, r *http.Request, status string) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.LoanDecisionPayload
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if status == models.InventoryRequestRejected && payload.Reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(payload.Reason) > maxLoanTextLength {
+		http.Error(w, fmt.Sprintf("reason must be at most %d characters", maxLoanTextLength), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	loan, ok := h.findLoan(ctx, w, r, status)
+	if !ok {
+		return
+	}
+
+	// Re-check availability at approval time. The item lock makes the check
+	// and the update below a single step, so approvals can never overbook.
+	if status == models.InventoryRequestApproved {
+		lock, ok := h.lockItem(ctx, w, *loan.ItemID)
+		if !ok {
+			return
+		}
+		defer lock.Release(context.Background())
+
+		if !h.ensureAvailable(ctx, w, loan, *loan.NeededFrom, *loan.NeededUntil) {
+			return
+		}
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"status":          status,
+		"decided_by":      claims.UserID,
+		"decided_at":      now,
+		"decision_reason": payload.Reason,
+	}
+	if !h.updateLoan(ctx, w, loan, set) {
+		return
+	}
+
+	loan.Status = status
+	loan.DecidedBy = &claims.UserID
+	loan.DecidedAt = &now
+	loan.DecisionReason = payload.Reason
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loan)
+}
+
+// PickupLoan records that the units of an approved loan were handed over.
+// Picking up before the loan period starts is allowed when the units are
+// free until then. For items with tagged units, unitIds must name exactly
+// as many available units as the loan's quantity.
+func (h *LoanHandler) PickupLoan(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	payload, ok := decodeHandover(w, r)
+	if !ok {
+		return
+	}
+	if payload.Condition != "" {
+		http.Error(w, "condition is only recorded on return", http.StatusBadRequest)
+		return
+	}
+	unitIDs := make([]primitive.ObjectID, 0, len(payload.UnitIDs))
+	for _, value := range payload.UnitIDs {
+		unitID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			http.Error(w, "Invalid Unit ID format", http.StatusBadRequest)
+			return
+		}
+		if slices.Contains(unitIDs, unitID) {
+			http.Error(w, "unitIds must not repeat a unit", http.StatusBadRequest)
+			return
+		}
+		unitIDs = append(unitIDs, unitID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	loan, ok := h.findLoan(ctx, w, r, models.InventoryRequestPickedUp)
+	if !ok {
+		return
+	}
+
+	tracked, err := trackedUnits(ctx, h.db, *loan.ItemID)
+	if err != nil {
+		http.Error(w, "Failed to count item units", http.StatusInternalServerError)
+		return
+	}
+	switch {
+	case tracked > 0 && len(unitIDs) != loan.Quantity:
+		http.Error(w, fmt.Sprintf("unitIds must list exactly %d unit(s) of the item", loan.Quantity), http.StatusBadRequest)
+		return
+	case tracked == 0 && len(unitIDs) > 0:
+		http.Error(w, "This item has no tagged units", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if now.Before(*loan.NeededFrom) {
+		lock, ok := h.lockItem(ctx, w, *loan.ItemID)
+		if !ok {
+			return
+		}
+		defer lock.Release(context.Background())
+
+		if !h.ensureAvailable(ctx, w, loan, now, *loan.NeededFrom) {
+			return
+		}
+	}
+
+	if err := assignUnits(ctx, h.db, loan.ID, *loan.ItemID, unitIDs, now); err != nil {
+		if errors.Is(err, errUnitUnavailable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("ERROR: Failed to assign units to loan %s: %v", loan.ID.Hex(), err)
+		http.Error(w, "Failed to assign units", http.StatusInternalServerError)
+		return
+	}
+
+	set := bson.M{
+		"status":       models.InventoryRequestPickedUp,
+		"picked_up_at": now,
+		"picked_up_by": claims.UserID,
+		"pickup_notes": payload.Notes,
+	}
+	if len(unitIDs) > 0 {
+		set["unit_ids"] = unitIDs
+	}
+	if !h.updateLoan(ctx, w, loan, set) {
+		if err := releaseUnits(context.Background(), h.db, loan.ID, unitIDs, bson.M{}); err != nil {
+			log.Printf("ERROR: Failed to release units of loan %s: %v", loan.ID.Hex(), err)
+		}
+		return
+	}
+
+	events := make([]models.AssetUnitEvent, len(unitIDs))
+	for i, unitID := range unitIDs {
+		events[i] = models.AssetUnitEvent{
+			UnitID:    unitID,
+			Type:      models.AssetUnitEventLoaned,
+			LoanID:    &loan.ID,
+			Notes:     payload.Notes,
+			ActorID:   &claims.UserID,
+			CreatedAt: now,
+		}
+	}
+	recordUnitEvents(ctx, h.db, events...)
+
+	loan.Status = models.InventoryRequestPickedUp
+	loan.PickedUpAt = &now
+	loan.PickedUpBy = &claims.UserID
+	loan.PickupNotes = payload.Notes
+	if len(unitIDs) > 0 {
+		loan.UnitIDs = unitIDs
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loan)
+}
+
+// ReturnLoan records that the units of a picked up or overdue loan came back,
+// and in which condition.
+func (h *LoanHandler) ReturnLoan(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	payload, ok := decodeHandover(w, r)
+	if !ok {
+		return
+	}
+	if len(payload.UnitIDs) > 0 {
+		http.Error(w, "unitIds are only recorded on pickup", http.StatusBadRequest)
+		return
+	}
+	if payload.Condition == "" {
+		payload.Condition = models.ItemConditionGood
+	}
+	if !slices.Contains(itemConditions, payload.Condition) {
+		http.Error(w, "condition must be one of good, fair or damaged", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	loan, ok := h.findLoan(ctx, w, r, models.InventoryRequestReturned)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"status":           models.InventoryRequestReturned,
+		"returned_at":      now,
+		"returned_to":      claims.UserID,
+		"return_condition": payload.Condition,
+		"return_notes":     payload.Notes,
+	}
+	if !h.updateLoan(ctx, w, loan, set) {
+		return
+	}
+
+	// The loan is already returned, so a failure here only leaves its units
+	// marked on loan; it is logged for an admin to fix by hand.
+	unitSet := bson.M{"condition": payload.Condition}
+	if err := releaseUnits(ctx, h.db, loan.ID, loan.UnitIDs, unitSet); err != nil {
+		log.Printf("ERROR: Failed to release units of loan %s: %v", loan.ID.Hex(), err)
+	}
+	events := make([]models.AssetUnitEvent, len(loan.UnitIDs))
+	for i, unitID := range loan.UnitIDs {
+		events[i] = models.AssetUnitEvent{
+			UnitID:    unitID,
+			Type:      models.AssetUnitEventReturned,
+			LoanID:    &loan.ID,
+			Condition: payload.Condition,
+			Notes:     payload.Notes,
+			ActorID:   &claims.UserID,
+			CreatedAt: now,
+		}
+	}
+	recordUnitEvents(ctx, h.db, events...)
+
+	loan.Status = models.InventoryRequestReturned
+	loan.ReturnedAt = &now
+	loan.ReturnedTo = &claims.UserID
+	loan.ReturnCondition = payload.Condition
+	loan.ReturnNotes = payload.Notes
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loan)
+}
+
+// decodeHandover reads and validates an optional pickup or return payload.
+func decodeHandover(w http.ResponseWriter, r *http.Request) (models.LoanHandoverPayload, bool) {
+	var payload models.LoanHandoverPayload
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return payload, false
+		}
+	}
+	payload.Condition = strings.ToLower(strings.TrimSpace(payload.Condition))
+	payload.Notes = strings.TrimSpace(payload.Notes)
+	if utf8.RuneCountInString(payload.Notes) > maxLoanTextLength {
+		http.Error(w, fmt.Sprintf("notes must be at most %d characters", maxLoanTextLength), http.StatusBadRequest)
+		return payload, false
+	}
+	return payload, true
+}
+
+// findLoan loads the loan named in the URL and checks that it may be moved
+// to status. Requests without an item or loan period, such as those made
+// before loans could be requested online, cannot be managed here.
+func (h *LoanHandler) findLoan(ctx context.Context, w http.ResponseWriter, r *http.Request, status string) (models.InventoryRequest, bool) {
+	var loan models.InventoryRequest
+	loanID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Loan ID format", http.StatusBadRequest)
+		return loan, false
+	}
+
+	err = h.db.Collection("inventory_requests").FindOne(ctx, bson.M{"_id": loanID}).Decode(&loan)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Loan request not found", http.StatusNotFound)
+			return loan, false
+		}
+		http.Error(w, "Failed to retrieve loan request", http.StatusInternalServerError)
+		return loan, false
+	}
+	if loan.ItemID == nil || loan.NeededFrom == nil || loan.NeededUntil == nil {
+		http.Error(w, "This request has no loan period and cannot be managed here", http.StatusConflict)
+		return loan, false
+	}
+
+	if !slices.Contains(loanTransitions[status], loan.Status) {
+		http.Error(w, fmt.Sprintf("Cannot change loan request from %s to %s", loan.Status, status), http.StatusConflict)
+		return loan, false
+	}
+	return loan, true
+}
+
+// findItem loads an item that can still be borrowed.
+func (h *LoanHandler) findItem(ctx context.Context, w http.ResponseWriter, itemID primitive.ObjectID) (models.InventoryItem, bool) {
+	var item models.InventoryItem
+	err := h.db.Collection("inventory_items").FindOne(ctx, bson.M{"_id": itemID, "deleted_at": nil}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Item not found", http.StatusNotFound)
+			return item, false
+		}
+		http.Error(w, "Failed to retrieve item", http.StatusInternalServerError)
+		return item, false
+	}
+	return item, true
+}
+
+// lockItem takes the item lock, answering 503 when it is busy.
+func (h *LoanHandler) lockItem(ctx context.Context, w http.ResponseWriter, itemID primitive.ObjectID) (*database.Lock, bool) {
+	lock, err := lockItem(ctx, h.db, itemID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock item %s: %v", itemID.Hex(), err)
+		http.Error(w, "The item is busy, please try again", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return lock, true
+}
+
+// ensureAvailable checks that the loan's units are free in [from, until),
+// not counting the loan itself. Callers must hold the item lock.
+func (h *LoanHandler) ensureAvailable(ctx context.Context, w http.ResponseWriter, loan models.InventoryRequest, from, until time.Time) bool {
+	item, ok := h.findItem(ctx, w, *loan.ItemID)
+	if !ok {
+		return false
+	}
+	used, err := loanUsage(ctx, h.db, item.ID, from, until, loan.ID)
+	if err != nil {
+		log.Printf("ERROR: Failed to compute usage of item %s: %v", item.ID.Hex(), err)
+		http.Error(w, "Failed to determine item availability", http.StatusInternalServerError)
+		return false
+	}
+	lendable, err := lendableQuantity(ctx, h.db, item)
+	if err != nil {
+		log.Printf("ERROR: Failed to count lendable units of item %s: %v", item.ID.Hex(), err)
+		http.Error(w, "Failed to determine item availability", http.StatusInternalServerError)
+		return false
+	}
+	if available := lendable - used; loan.Quantity > available {
+		http.Error(w, fmt.Sprintf("Only %d unit(s) available for the requested period", max(available, 0)), http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// updateLoan applies set to the loan. Filtering on the current status makes
+// the transition atomic: if another admin changed the loan in the meantime,
+// nothing is updated.
+func (h *LoanHandler) updateLoan(ctx context.Context, w http.ResponseWriter, loan models.InventoryRequest, set bson.M) bool {
+	result, err := h.db.Collection("inventory_requests").UpdateOne(ctx,
+		bson.M{"_id": loan.ID, "status": loan.Status},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		log.Printf("ERROR: Failed to update loan request %s: %v", loan.ID.Hex(), err)
+		http.Error(w, "Failed to update loan request", http.StatusInternalServerError)
+		return false
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Loan request was modified by another request, please retry", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// itemLockTTL bounds how long a crashed request can keep an item locked. It is
+// longer than the 5 second timeout used for the work done under the lock.
+const itemLockTTL = 10 * time.Second
+
+// lockItem serialises stock writes for an item. Every code path that checks
+// availability and then approves or hands over a loan, or that reduces or
+// removes the item, must hold it.
+func lockItem(ctx context.Context, db *mongo.Database, itemID primitive.ObjectID) (*database.Lock, error) {
+	return database.AcquireLock(ctx, db, "item:"+itemID.Hex(), itemLockTTL)
+}
+
+// loanPeriod returns when a holding loan keeps its units out of stock. Units
+// handed over early are out from the pickup, and units not yet back after the
+// loan period stay out until returned, which open reports. Loans without a
+// period hold their units indefinitely.
+func loanPeriod(loan models.InventoryRequest, now time.Time) (start, end time.Time, open bool) {
+	if loan.NeededFrom != nil {
+		start = *loan.NeededFrom
+	}
+	if loan.PickedUpAt != nil && loan.PickedUpAt.Before(start) {
+		start = *loan.PickedUpAt
+	}
+	if loan.NeededUntil == nil {
+		return start, end, true
+	}
+	end = *loan.NeededUntil
+	if loan.Status != models.InventoryRequestApproved && !now.Before(end) {
+		return start, end, true
+	}
+	return start, end, false
+}
+
+// holdingLoans returns the loans matching filter that hold units of their item.
+func holdingLoans(ctx context.Context, db *mongo.Database, filter bson.M) ([]models.InventoryRequest, error) {
+	filter["status"] = bson.M{"$in": models.InventoryHoldingStatuses}
+	cursor, err := db.Collection("inventory_requests").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var loans []models.InventoryRequest
+	if err := cursor.All(ctx, &loans); err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+// loanUsage returns the largest number of units of the item that holding
+// loans have out at any one moment in [from, until). A zero until leaves the
+// range open-ended. The loan exclude is not counted.
+func loanUsage(ctx context.Context, db *mongo.Database, itemID primitive.ObjectID, from, until time.Time, exclude primitive.ObjectID) (int, error) {
+	filter := bson.M{"item_id": itemID}
+	if !exclude.IsZero() {
+		filter["_id"] = bson.M{"$ne": exclude}
+	}
+	loans, err := holdingLoans(ctx, db, filter)
+	if err != nil {
+		return 0, err
+	}
+	return peakUsage(loans, from, until, time.Now()), nil
+}
+
+// peakUsage returns the largest number of units the loans have out at any one
+// moment in [from, until), as of now. A zero until leaves the range
+// open-ended.
+func peakUsage(loans []models.InventoryRequest, from, until, now time.Time) int {
+	type change struct {
+		at    time.Time
+		delta int
+	}
+	var changes []change
+	for _, loan := range loans {
+		start, end, open := loanPeriod(loan, now)
+		if start.Before(from) {
+			start = from
+		}
+		if !until.IsZero() && (open || end.After(until)) {
+			end, open = until, false
+		}
+		if !open && !start.Before(end) {
+			continue
+		}
+		changes = append(changes, change{start, loan.Quantity})
+		if !open {
+			changes = append(changes, change{end, -loan.Quantity})
+		}
+	}
+
+	// Periods are half-open, so at equal times returns count before pickups.
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].at.Equal(changes[j].at) {
+			return changes[i].delta < changes[j].delta
+		}
+		return changes[i].at.Before(changes[j].at)
+	})
+
+	used, peak := 0, 0
+	for _, c := range changes {
+		used += c.delta
+		peak = max(peak, used)
+	}
+	return peak
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+)
+
+func TestPeakUsage(t *testing.T) {
+	now := time.Date(2025, 9, 1, 8, 0, 0, 0, department.Location)
+	day := func(d int) time.Time { return now.AddDate(0, 0, d) }
+	loan := func(status string, quantity, from, until int) models.InventoryRequest {
+		start, end := day(from), day(until)
+		return models.InventoryRequest{Status: status, Quantity: quantity, NeededFrom: &start, NeededUntil: &end}
+	}
+	pickedUp := func(l models.InventoryRequest, at int) models.InventoryRequest {
+		t := day(at)
+		l.PickedUpAt = &t
+		return l
+	}
+
+	tests := []struct {
+		name        string
+		loans       []models.InventoryRequest
+		from, until time.Time
+		want        int
+	}{
+		{"no loans", nil, day(1), day(2), 0},
+		{"overlapping loans add up", []models.InventoryRequest{
+			loan(models.InventoryRequestApproved, 2, 1, 4),
+			loan(models.InventoryRequestApproved, 3, 3, 6),
+		}, day(0), day(10), 5},
+		{"back-to-back loans do not overlap", []models.InventoryRequest{
+			loan(models.InventoryRequestApproved, 2, 1, 3),
+			loan(models.InventoryRequestApproved, 3, 3, 6),
+		}, day(0), day(10), 3},
+		{"peak is the largest moment, not the sum", []models.InventoryRequest{
+			loan(models.InventoryRequestApproved, 4, 1, 2),
+			loan(models.InventoryRequestApproved, 1, 3, 4),
+			loan(models.InventoryRequestApproved, 2, 3, 5),
+		}, day(0), day(10), 4},
+		{"loans outside the range are ignored", []models.InventoryRequest{
+			loan(models.InventoryRequestApproved, 2, 1, 3),
+			loan(models.InventoryRequestApproved, 3, 6, 8),
+		}, day(3), day(6), 0},
+		{"open-ended range", []models.InventoryRequest{
+			loan(models.InventoryRequestApproved, 2, 20, 30),
+		}, day(0), time.Time{}, 2},
+		{"overdue loans hold units indefinitely", []models.InventoryRequest{
+			pickedUp(loan(models.InventoryRequestOverdue, 2, -5, -1), -5),
+			loan(models.InventoryRequestApproved, 1, 30, 31),
+		}, day(30), day(31), 3},
+		{"picked-up loans past their end hold units", []models.InventoryRequest{
+			pickedUp(loan(models.InventoryRequestPickedUp, 2, -5, -1), -5),
+		}, day(10), day(11), 2},
+		{"early pickup starts the loan", []models.InventoryRequest{
+			pickedUp(loan(models.InventoryRequestPickedUp, 2, 5, 8), 0),
+		}, day(1), day(2), 2},
+	}
+	for _, tt := range tests {
+		if got := peakUsage(tt.loans, tt.from, tt.until, now); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaintenanceHandler handles admin scheduling of room maintenance windows.
+type MaintenanceHandler struct {
+	db *mongo.Database
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler.
+func NewMaintenanceHandler(db *mongo.Database) *MaintenanceHandler {
+	return &MaintenanceHandler{db: db}
+}
+
+// GetRoomMaintenance lists the maintenance windows of a room, newest first.
+func (h *MaintenanceHandler) GetRoomMaintenance(w http.ResponseWriter, r *http.Request) {
+	roomID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Room ID format", http.StatusBadRequest)
+		return
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "start_time", Value: -1}})
+
+	cursor, err := h.db.Collection("maintenance_windows").Find(context.TODO(), bson.M{"room_id": roomID}, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve maintenance windows", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var windows []models.MaintenanceWindow
+	if err = cursor.All(context.TODO(), &windows); err != nil {
+		http.Error(w, "Failed to parse maintenance windows", http.StatusInternalServerError)
+		return
+	}
+
+	if windows == nil {
+		windows = []models.MaintenanceWindow{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(windows)
+}
+
+// CreateRoomMaintenance schedules a maintenance window for a room. Approved
+// reservations that overlap it are returned so admins can follow up on them.
+// The room lock is held across the insert and that lookup, so a reservation
+// approved at the same moment is either refused or reported.
+func (h *MaintenanceHandler) CreateRoomMaintenance(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	roomID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Room ID format", http.StatusBadRequest)
+		return
+	}
+	count, err := h.db.Collection("rooms").CountDocuments(context.TODO(), bson.M{"_id": roomID, "deleted_at": nil})
+	if err != nil {
+		http.Error(w, "Failed to retrieve room data", http.StatusInternalServerError)
+		return
+	}
+	if count == 0 {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	window, ok := decodeMaintenanceWindow(w, r)
+	if !ok {
+		return
+	}
+	window.ID = primitive.NewObjectID()
+	window.RoomID = roomID
+	window.CreatedBy = claims.UserID
+	window.CreatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lock, err := lockRoom(ctx, h.db, roomID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock room %s: %v", roomID.Hex(), err)
+		http.Error(w, "The room is busy, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	defer lock.Release(context.Background())
+
+	if _, err := h.db.Collection("maintenance_windows").InsertOne(ctx, window); err != nil {
+		log.Printf("ERROR: Failed to insert maintenance window: %v", err)
+		http.Error(w, "Failed to schedule maintenance", http.StatusInternalServerError)
+		return
+	}
+
+	affected, err := h.affectedReservations(ctx, window)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"maintenance":          window,
+		"affectedReservations": affected,
+	})
+}
+
+// UpdateMaintenance reschedules or renames a maintenance window, under the
+// room lock like CreateRoomMaintenance.
+func (h *MaintenanceHandler) UpdateMaintenance(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Maintenance ID format", http.StatusBadRequest)
+		return
+	}
+
+	window, ok := decodeMaintenanceWindow(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var current models.MaintenanceWindow
+	err = h.db.Collection("maintenance_windows").FindOne(ctx, bson.M{"_id": objID}).Decode(&current)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Maintenance window not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve maintenance window", http.StatusInternalServerError)
+		return
+	}
+
+	lock, err := lockRoom(ctx, h.db, current.RoomID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock room %s: %v", current.RoomID.Hex(), err)
+		http.Error(w, "The room is busy, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	defer lock.Release(context.Background())
+
+	update := bson.M{"$set": bson.M{
+		"title":      window.Title,
+		"notes":      window.Notes,
+		"start_time": window.StartTime,
+		"end_time":   window.EndTime,
+	}}
+	findOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var saved models.MaintenanceWindow
+	err = h.db.Collection("maintenance_windows").FindOneAndUpdate(ctx, bson.M{"_id": objID}, update, findOptions).Decode(&saved)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Maintenance window not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("ERROR: Failed to update maintenance window %s: %v", objID.Hex(), err)
+		http.Error(w, "Failed to update maintenance window", http.StatusInternalServerError)
+		return
+	}
+
+	affected, err := h.affectedReservations(ctx, saved)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"maintenance":          saved,
+		"affectedReservations": affected,
+	})
+}
+
+// DeleteMaintenance removes a maintenance window.
+func (h *MaintenanceHandler) DeleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Maintenance ID format", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Collection("maintenance_windows").DeleteOne(context.TODO(), bson.M{"_id": objID})
+	if err != nil {
+		http.Error(w, "Failed to delete maintenance window", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Maintenance window not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Maintenance window deleted"})
+}
+
+// affectedReservations returns the approved reservations overlapping a maintenance window.
+func (h *MaintenanceHandler) affectedReservations(ctx context.Context, window models.MaintenanceWindow) ([]models.Reservation, error) {
+	filter := overlapFilter(window.RoomID, window.StartTime, window.EndTime)
+	filter["status"] = models.ReservationApproved
+
+	cursor, err := h.db.Collection("reservations").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var reservations []models.Reservation
+	if err = cursor.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+	if reservations == nil {
+		reservations = []models.Reservation{}
+	}
+	return reservations, nil
+}
+
+// decodeMaintenanceWindow parses and validates a MaintenanceWindowPayload,
+// writing an error response and returning false if it is invalid.
+func decodeMaintenanceWindow(w http.ResponseWriter, r *http.Request) (models.MaintenanceWindow, bool) {
+	var payload models.MaintenanceWindowPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return models.MaintenanceWindow{}, false
+	}
+
+	window := models.MaintenanceWindow{
+		Title: strings.TrimSpace(payload.Title),
+		Notes: strings.TrimSpace(payload.Notes),
+	}
+	if window.Title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return window, false
+	}
+
+	var err error
+	if window.StartTime, err = time.Parse(time.RFC3339, payload.StartTime); err != nil {
+		http.Error(w, "Invalid Start Time format", http.StatusBadRequest)
+		return window, false
+	}
+	if window.EndTime, err = time.Parse(time.RFC3339, payload.EndTime); err != nil {
+		http.Error(w, "Invalid End Time format", http.StatusBadRequest)
+		return window, false
+	}
+	if !window.EndTime.After(window.StartTime) {
+		http.Error(w, "End time must be after start time", http.StatusBadRequest)
+		return window, false
+	}
+	return window, true
+}
+
+// findMaintenance returns the maintenance windows of a room that intersect [from, to).
+func findMaintenance(ctx context.Context, db *mongo.Database, roomID primitive.ObjectID, from, to time.Time) ([]models.BusyInterval, error) {
+	filter := bson.M{
+		"room_id":    roomID,
+		"start_time": bson.M{"$lt": to},
+		"end_time":   bson.M{"$gt": from},
+	}
+
+	cursor, err := db.Collection("maintenance_windows").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var windows []models.MaintenanceWindow
+	if err = cursor.All(ctx, &windows); err != nil {
+		return nil, err
+	}
+
+	busy := make([]models.BusyInterval, 0, len(windows))
+	for _, window := range windows {
+		busy = append(busy, models.BusyInterval{
+			Start: window.StartTime,
+			End:   window.EndTime,
+			Kind:  models.BusyMaintenance,
+			Title: window.Title,
+		})
+	}
+	return busy, nil
+}
+
+// deriveRoomStatuses sets each room's Status from what is happening right now:
+// an active maintenance window wins over a running approved reservation, and
+// rooms with neither are Available.
+func deriveRoomStatuses(ctx context.Context, db *mongo.Database, rooms []models.Room) error {
+	if len(rooms) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(rooms))
+	for i, room := range rooms {
+		ids[i] = room.ID
+	}
+	now := time.Now()
+	current := bson.M{
+		"room_id":    bson.M{"$in": ids},
+		"start_time": bson.M{"$lte": now},
+		"end_time":   bson.M{"$gt": now},
+	}
+
+	underMaintenance, err := db.Collection("maintenance_windows").Distinct(ctx, "room_id", current)
+	if err != nil {
+		return err
+	}
+	current["status"] = models.ReservationApproved
+	inUse, err := db.Collection("reservations").Distinct(ctx, "room_id", current)
+	if err != nil {
+		return err
+	}
+
+	maintenanceSet := make(map[primitive.ObjectID]bool, len(underMaintenance))
+	for _, id := range underMaintenance {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			maintenanceSet[oid] = true
+		}
+	}
+	inUseSet := make(map[primitive.ObjectID]bool, len(inUse))
+	for _, id := range inUse {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			inUseSet[oid] = true
+		}
+	}
+
+	for i := range rooms {
+		switch {
+		case maintenanceSet[rooms[i].ID]:
+			rooms[i].Status = models.RoomUnderMaintenance
+		case inUseSet[rooms[i].ID]:
+			rooms[i].Status = models.RoomInUse
+		default:
+			rooms[i].Status = models.RoomAvailable
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationHandler handles reading the caller's in-app notifications.
+type NotificationHandler struct {
+	db *mongo.Database
+}
+
+// NewNotificationHandler creates a new NotificationHandler.
+func NewNotificationHandler(db *mongo.Database) *NotificationHandler {
+	return &NotificationHandler{db: db}
+}
+
+// GetNotifications returns the caller's notifications, newest first. Pass
+// unread=true to only return unread ones.
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	filter := bson.M{"user_id": claims.UserID}
+	if r.URL.Query().Get("unread") == "true" {
+		filter["read_at"] = nil
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	findOptions.SetLimit(100)
+
+	cursor, err := h.db.Collection("notifications").Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notifications", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var notifications []models.Notification
+	if err = cursor.All(context.TODO(), &notifications); err != nil {
+		http.Error(w, "Failed to parse notifications", http.StatusInternalServerError)
+		return
+	}
+
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// MarkNotificationRead marks one of the caller's notifications as read.
+func (h *NotificationHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Notification ID format", http.StatusBadRequest)
+		return
+	}
+
+	filter := bson.M{"_id": objID, "user_id": claims.UserID}
+	result, err := h.db.Collection("notifications").UpdateOne(context.TODO(), filter, bson.M{"$set": bson.M{"read_at": time.Now()}})
+	if err != nil {
+		http.Error(w, "Failed to update notification", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Notification marked as read"})
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxProfileTextLength = 100
+
+var (
+	phonePattern          = regexp.MustCompile(`^\+?[0-9][0-9 -]{6,18}[0-9]$`)
+	identityNumberPattern = regexp.MustCompile(`^[0-9]{8,20}$`)
+	userTypes             = []string{models.UserTypeStudent, models.UserTypeLecturer, models.UserTypeStaff}
+)
+
+// ProfileHandler handles reading and editing user profiles.
+type ProfileHandler struct {
+	db *mongo.Database
+}
+
+// NewProfileHandler creates a new ProfileHandler.
+func NewProfileHandler(db *mongo.Database) *ProfileHandler {
+	return &ProfileHandler{db: db}
+}
+
+// GetMe returns the caller's account and profile.
+func (h *ProfileHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": claims.UserID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateMe edits the caller's self-editable profile fields. Academic
+// identity fields can only be changed by an admin.
+func (h *ProfileHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	payload, ok := decodeProfile(w, r)
+	if !ok {
+		return
+	}
+	if field := adminOnlyProfileField(payload); field != "" {
+		http.Error(w, fmt.Sprintf("Forbidden: %s can only be changed by an administrator", field), http.StatusForbidden)
+		return
+	}
+
+	h.updateProfile(w, claims.UserID, payload)
+}
+
+// UpdateUserProfile lets an admin edit any profile field of a user. Admins
+// cannot edit the profile of someone more privileged.
+func (h *ProfileHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid User ID format", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	err = h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return
+	}
+	if !auth.HasRole(claims.Role, user.Role) {
+		http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	payload, ok := decodeProfile(w, r)
+	if !ok {
+		return
+	}
+
+	h.updateProfile(w, userID, payload)
+}
+
+// updateProfile applies the payload to the user and answers with the result.
+func (h *ProfileHandler) updateProfile(w http.ResponseWriter, userID primitive.ObjectID, payload models.ProfilePayload) {
+	set, unset := profileUpdate(payload)
+	if len(set) == 0 && len(unset) == 0 {
+		http.Error(w, "No profile fields to update", http.StatusBadRequest)
+		return
+	}
+	set["updated_at"] = time.Now()
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	findOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var saved models.User
+	err := h.db.Collection("users").FindOneAndUpdate(context.TODO(), bson.M{"_id": userID}, update, findOptions).Decode(&saved)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Identity number already in use", http.StatusConflict)
+			return
+		}
+		log.Printf("ERROR: Failed to update profile of user %s: %v", userID.Hex(), err)
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// decodeProfile reads and validates a profile payload, trimming every field.
+func decodeProfile(w http.ResponseWriter, r *http.Request) (models.ProfilePayload, bool) {
+	var payload models.ProfilePayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return payload, false
+	}
+
+	for _, field := range []*string{payload.FullName, payload.Phone, payload.AvatarURL,
+		payload.UserType, payload.IdentityNumber, payload.StudyProgram, payload.Faculty} {
+		if field != nil {
+			*field = strings.TrimSpace(*field)
+		}
+	}
+
+	switch {
+	case tooLong(payload.FullName):
+		http.Error(w, fmt.Sprintf("fullName must be at most %d characters", maxProfileTextLength), http.StatusBadRequest)
+	case payload.Phone != nil && *payload.Phone != "" && !phonePattern.MatchString(*payload.Phone):
+		http.Error(w, "phone must be 8-20 digits, optionally starting with +", http.StatusBadRequest)
+	case payload.AvatarURL != nil && *payload.AvatarURL != "" && !isHTTPURL(*payload.AvatarURL):
+		http.Error(w, "avatarUrl must be an http or https URL", http.StatusBadRequest)
+	case payload.UserType != nil && *payload.UserType != "" && !slices.Contains(userTypes, *payload.UserType):
+		http.Error(w, "userType must be one of student, lecturer or staff", http.StatusBadRequest)
+	case payload.IdentityNumber != nil && *payload.IdentityNumber != "" && !identityNumberPattern.MatchString(*payload.IdentityNumber):
+		http.Error(w, "identityNumber must be 8-20 digits (NIM or NIP)", http.StatusBadRequest)
+	case tooLong(payload.StudyProgram):
+		http.Error(w, fmt.Sprintf("studyProgram must be at most %d characters", maxProfileTextLength), http.StatusBadRequest)
+	case tooLong(payload.Faculty):
+		http.Error(w, fmt.Sprintf("faculty must be at most %d characters", maxProfileTextLength), http.StatusBadRequest)
+	default:
+		return payload, true
+	}
+	return payload, false
+}
+
+// adminOnlyProfileField returns the name of the first field in the payload
+// that only admins may change, or "" when there is none.
+func adminOnlyProfileField(payload models.ProfilePayload) string {
+	switch {
+	case payload.UserType != nil:
+		return "userType"
+	case payload.IdentityNumber != nil:
+		return "identityNumber"
+	case payload.StudyProgram != nil:
+		return "studyProgram"
+	case payload.Faculty != nil:
+		return "faculty"
+	}
+	return ""
+}
+
+// profileUpdate splits the payload into fields to set and, for empty
+// values, fields to remove.
+func profileUpdate(payload models.ProfilePayload) (set, unset bson.M) {
+	set, unset = bson.M{}, bson.M{}
+	for key, value := range map[string]*string{
+		"full_name":       payload.FullName,
+		"phone":           payload.Phone,
+		"avatar_url":      payload.AvatarURL,
+		"user_type":       payload.UserType,
+		"identity_number": payload.IdentityNumber,
+		"study_program":   payload.StudyProgram,
+		"faculty":         payload.Faculty,
+	} {
+		switch {
+		case value == nil:
+		case *value == "":
+			unset[key] = ""
+		default:
+			set[key] = *value
+		}
+	}
+	return set, unset
+}
+
+func tooLong(value *string) bool {
+	return value != nil && utf8.RuneCountInString(*value) > maxProfileTextLength
+}
+
+// isHTTPURL reports whether value is an absolute http or https URL.
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
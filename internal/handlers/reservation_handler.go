@@ -3,16 +3,25 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/database"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/policy"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // ReservationHandler handles requests for reservation data.
@@ -65,36 +74,47 @@ func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Re
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"room_id": roomObjID,
-		"status":  "Approved",
-		"$or": []bson.M{
-			{"start_time": bson.M{"$gte": startTime, "$lt": endTime}},
-			{"end_time": bson.M{"$gt": startTime, "$lte": endTime}},
-			{"start_time": bson.M{"$lte": startTime}, "end_time": bson.M{"$gte": endTime}},
-		},
+	// Hold the locks across the checks and the insert below so that a
+	// concurrent approval or booking cannot slip in between them.
+	release, err := lockBooking(ctx, h.db, roomObjID, claims.UserID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock room %s: %v", roomObjID.Hex(), err)
+		http.Error(w, "The room is busy, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	reservationID := primitive.NewObjectID()
+	violations, err := h.checkPolicy(ctx, claims, roomObjID, reservationID, []models.TimeInterval{{Start: startTime, End: endTime}})
+	if err != nil {
+		writeBookingCheckError(w, err)
+		return
+	}
+	if len(violations) > 0 {
+		writePolicyViolations(w, violations)
+		return
 	}
 
-	count, err := collection.CountDocuments(ctx, filter)
+	conflicts, err := h.findConflicts(ctx, roomObjID, []models.TimeInterval{{Start: startTime, End: endTime}})
 	if err != nil {
-		http.Error(w, "Failed to check for booking conflicts", http.StatusInternalServerError)
+		writeBookingCheckError(w, err)
 		return
 	}
-	if count > 0 {
+	if len(conflicts) > 0 {
 		http.Error(w, "The selected time slot is unavailable due to a conflict.", http.StatusConflict)
 		return
 	}
 
 	// --- Create Reservation ---
 	newReservation := models.Reservation{
-		ID:          primitive.NewObjectID(),
+		ID:          reservationID,
 		RoomID:      roomObjID,
 		UserID:      claims.UserID,
 		Purpose:     payload.Purpose,
 		Description: payload.Description,
 		StartTime:   startTime,
 		EndTime:     endTime,
-		Status:      "Pending",
+		Status:      models.ReservationPending,
 		CreatedAt:   time.Now(),
 	}
 
@@ -119,3 +139,756 @@ func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Re
 		"reservationId": newReservation.ID.Hex(),
 	})
 }
+
+// ListMyReservations returns the caller's reservations. Results can be filtered
+// by status, room and a date range (from/to, RFC3339 or YYYY-MM-DD).
+func (h *ReservationHandler) ListMyReservations(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := bson.M{"user_id": claims.UserID}
+
+	if status := query.Get("status"); status != "" {
+		filter["status"] = status
+	}
+	if roomID := query.Get("roomId"); roomID != "" {
+		roomObjID, err := primitive.ObjectIDFromHex(roomID)
+		if err != nil {
+			http.Error(w, "Invalid Room ID format", http.StatusBadRequest)
+			return
+		}
+		filter["room_id"] = roomObjID
+	}
+	if from := query.Get("from"); from != "" {
+		fromTime, err := parseTimeParam(from)
+		if err != nil {
+			http.Error(w, "Invalid 'from' format", http.StatusBadRequest)
+			return
+		}
+		filter["end_time"] = bson.M{"$gt": fromTime}
+	}
+	if to := query.Get("to"); to != "" {
+		toTime, err := parseTimeParam(to)
+		if err != nil {
+			http.Error(w, "Invalid 'to' format", http.StatusBadRequest)
+			return
+		}
+		filter["start_time"] = bson.M{"$lt": toTime}
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "start_time", Value: -1}})
+
+	collection := h.db.Collection("reservations")
+	cursor, err := collection.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var reservations []models.Reservation
+	if err = cursor.All(context.TODO(), &reservations); err != nil {
+		http.Error(w, "Failed to parse reservations data", http.StatusInternalServerError)
+		return
+	}
+
+	if reservations == nil {
+		reservations = []models.Reservation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservations)
+}
+
+// GetMyReservation returns one of the caller's reservations with its room embedded.
+// Admins may fetch any reservation.
+func (h *ReservationHandler) GetMyReservation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	reservationID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Reservation ID format", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var detail models.ReservationWithRoom
+	err = h.db.Collection("reservations").FindOne(ctx, bson.M{"_id": reservationID}).Decode(&detail.Reservation)
+	if err == mongo.ErrNoDocuments || (err == nil && detail.UserID != claims.UserID && !claims.Can(auth.PermViewAllReservations)) {
+		// Reservations owned by someone else are reported as missing so IDs cannot be probed.
+		http.Error(w, "Reservation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservation", http.StatusInternalServerError)
+		return
+	}
+
+	var room models.Room
+	err = h.db.Collection("rooms").FindOne(ctx, bson.M{"_id": detail.RoomID}).Decode(&room)
+	if err == nil {
+		detail.Room = &room
+	} else if err != mongo.ErrNoDocuments {
+		http.Error(w, "Failed to retrieve room data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// CancelMyReservation lets the owner cancel a Pending or Approved reservation
+// that has not started yet.
+func (h *ReservationHandler) CancelMyReservation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	reservationID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Reservation ID format", http.StatusBadRequest)
+		return
+	}
+
+	collection := h.db.Collection("reservations")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var reservation models.Reservation
+	err = collection.FindOne(ctx, bson.M{"_id": reservationID, "user_id": claims.UserID}).Decode(&reservation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Reservation not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve reservation", http.StatusInternalServerError)
+		return
+	}
+
+	if reservation.Status != models.ReservationPending && reservation.Status != models.ReservationApproved {
+		http.Error(w, fmt.Sprintf("Cannot cancel a reservation that is %s", reservation.Status), http.StatusConflict)
+		return
+	}
+	now := time.Now()
+	if !now.Before(reservation.StartTime) {
+		http.Error(w, "Reservations can only be cancelled before they start", http.StatusConflict)
+		return
+	}
+
+	filter := bson.M{
+		"_id":        reservation.ID,
+		"user_id":    claims.UserID,
+		"status":     reservation.Status,
+		"start_time": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"status": models.ReservationCancelled, "cancelled_at": now}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		log.Printf("ERROR: Failed to cancel reservation %s: %v", reservation.ID.Hex(), err)
+		http.Error(w, "Failed to cancel reservation", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Reservation was modified by another request, please retry", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":       "Reservasi berhasil dibatalkan",
+		"reservationId": reservation.ID.Hex(),
+	})
+}
+
+// UpdateMyReservation edits one of the caller's upcoming reservations, which may
+// be a single occurrence of a series. Changing the time re-checks for conflicts
+// and sends the reservation back to Pending for a new approval.
+func (h *ReservationHandler) UpdateMyReservation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	reservationID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Reservation ID format", http.StatusBadRequest)
+		return
+	}
+
+	var payload models.UpdateReservationPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	collection := h.db.Collection("reservations")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var reservation models.Reservation
+	err = collection.FindOne(ctx, bson.M{"_id": reservationID, "user_id": claims.UserID}).Decode(&reservation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Reservation not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve reservation", http.StatusInternalServerError)
+		return
+	}
+
+	if reservation.Status != models.ReservationPending && reservation.Status != models.ReservationApproved {
+		http.Error(w, fmt.Sprintf("Cannot edit a reservation that is %s", reservation.Status), http.StatusConflict)
+		return
+	}
+	now := time.Now()
+	if !now.Before(reservation.StartTime) {
+		http.Error(w, "Reservations can only be edited before they start", http.StatusConflict)
+		return
+	}
+
+	currentStatus := reservation.Status
+	set := bson.M{}
+	unset := bson.M{}
+	if payload.Purpose != "" {
+		set["purpose"] = payload.Purpose
+		reservation.Purpose = payload.Purpose
+	}
+	if payload.Description != "" {
+		set["description"] = payload.Description
+		reservation.Description = payload.Description
+	}
+
+	startTime, endTime := reservation.StartTime, reservation.EndTime
+	if payload.StartTime != "" {
+		if startTime, err = time.Parse(time.RFC3339, payload.StartTime); err != nil {
+			http.Error(w, "Invalid Start Time format", http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.EndTime != "" {
+		if endTime, err = time.Parse(time.RFC3339, payload.EndTime); err != nil {
+			http.Error(w, "Invalid End Time format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !startTime.Equal(reservation.StartTime) || !endTime.Equal(reservation.EndTime) {
+		if !endTime.After(startTime) {
+			http.Error(w, "End time must be after start time", http.StatusBadRequest)
+			return
+		}
+		if !startTime.After(now) {
+			http.Error(w, "Start time must be in the future", http.StatusBadRequest)
+			return
+		}
+
+		release, err := lockBooking(ctx, h.db, reservation.RoomID, claims.UserID)
+		if err != nil {
+			log.Printf("ERROR: Failed to lock room %s: %v", reservation.RoomID.Hex(), err)
+			http.Error(w, "The room is busy, please try again", http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
+		// An occurrence still counts as part of its series.
+		booking := reservation.ID
+		if reservation.SeriesID != nil {
+			booking = *reservation.SeriesID
+		}
+		violations, err := h.checkPolicy(ctx, claims, reservation.RoomID, booking, []models.TimeInterval{{Start: startTime, End: endTime}}, reservation.ID)
+		if err != nil {
+			writeBookingCheckError(w, err)
+			return
+		}
+		if len(violations) > 0 {
+			writePolicyViolations(w, violations)
+			return
+		}
+
+		conflicts, err := h.findConflicts(ctx, reservation.RoomID, []models.TimeInterval{{Start: startTime, End: endTime}}, reservation.ID)
+		if err != nil {
+			writeBookingCheckError(w, err)
+			return
+		}
+		if len(conflicts) > 0 {
+			http.Error(w, "The selected time slot is unavailable due to a conflict.", http.StatusConflict)
+			return
+		}
+
+		set["start_time"] = startTime
+		set["end_time"] = endTime
+		set["status"] = models.ReservationPending
+		unset["decided_by"] = ""
+		unset["decided_at"] = ""
+		unset["decision_reason"] = ""
+		unset["reminder_sent_at"] = ""
+		unset["reminder_attempts"] = ""
+		unset["reminder_claimed_until"] = ""
+
+		reservation.StartTime = startTime
+		reservation.EndTime = endTime
+		reservation.Status = models.ReservationPending
+		reservation.DecidedBy = nil
+		reservation.DecidedAt = nil
+		reservation.DecisionReason = ""
+		reservation.ReminderSentAt = nil
+	}
+
+	if len(set) == 0 {
+		http.Error(w, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": reservation.ID, "status": currentStatus}, update)
+	if err != nil {
+		log.Printf("ERROR: Failed to update reservation %s: %v", reservation.ID.Hex(), err)
+		http.Error(w, "Failed to update reservation", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Reservation was modified by another request, please retry", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// reservationTransitions lists, for each target status, the statuses a
+// reservation must currently be in for an admin to move it there.
+var reservationTransitions = map[string][]string{
+	models.ReservationApproved: {models.ReservationPending},
+	models.ReservationRejected: {models.ReservationPending},
+	models.ReservationRevoked:  {models.ReservationApproved},
+}
+
+// ListReservations returns all reservations for admins, optionally filtered by status.
+func (h *ReservationHandler) ListReservations(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "start_time", Value: 1}})
+
+	collection := h.db.Collection("reservations")
+	cursor, err := collection.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var reservations []models.Reservation
+	if err = cursor.All(context.TODO(), &reservations); err != nil {
+		http.Error(w, "Failed to parse reservations data", http.StatusInternalServerError)
+		return
+	}
+
+	if reservations == nil {
+		reservations = []models.Reservation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservations)
+}
+
+// ApproveReservation approves a pending reservation after re-checking for conflicts.
+func (h *ReservationHandler) ApproveReservation(w http.ResponseWriter, r *http.Request) {
+	h.decideReservation(w, r, models.ReservationApproved)
+}
+
+// RejectReservation rejects a pending reservation. A reason is required.
+func (h *ReservationHandler) RejectReservation(w http.ResponseWriter, r *http.Request) {
+	h.decideReservation(w, r, models.ReservationRejected)
+}
+
+// RevokeReservation withdraws a previously approved reservation. A reason is required.
+func (h *ReservationHandler) RevokeReservation(w http.ResponseWriter, r *http.Request) {
+	h.decideReservation(w, r, models.ReservationRevoked)
+}
+
+// decideReservation moves a reservation to the given status, enforcing the
+// allowed transitions and recording who made the decision, when and why.
+func (h *ReservationHandler) decideReservation(w http.ResponseWriter, r *http.Request, status string) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	reservationID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Reservation ID format", http.StatusBadRequest)
+		return
+	}
+
+	var payload models.ReservationDecisionPayload
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if status != models.ReservationApproved && payload.Reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+
+	collection := h.db.Collection("reservations")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var reservation models.Reservation
+	err = collection.FindOne(ctx, bson.M{"_id": reservationID}).Decode(&reservation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Reservation not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve reservation", http.StatusInternalServerError)
+		return
+	}
+
+	allowedFrom := reservationTransitions[status]
+	if !slices.Contains(allowedFrom, reservation.Status) {
+		http.Error(w, fmt.Sprintf("Cannot change reservation from %s to %s", reservation.Status, status), http.StatusConflict)
+		return
+	}
+
+	// Re-check for conflicts at approval time, since other reservations for
+	// the same slot may have been approved after this one was submitted. The
+	// room lock makes the check and the update below a single step, so two
+	// overlapping reservations can never both be approved.
+	if status == models.ReservationApproved {
+		lock, err := lockRoom(ctx, h.db, reservation.RoomID)
+		if err != nil {
+			log.Printf("ERROR: Failed to lock room %s: %v", reservation.RoomID.Hex(), err)
+			http.Error(w, "The room is busy, please try again", http.StatusServiceUnavailable)
+			return
+		}
+		defer lock.Release(context.Background())
+
+		interval := models.TimeInterval{Start: reservation.StartTime, End: reservation.EndTime}
+		conflicts, err := h.findConflicts(ctx, reservation.RoomID, []models.TimeInterval{interval}, reservation.ID)
+		if err != nil {
+			writeBookingCheckError(w, err)
+			return
+		}
+		if len(conflicts) > 0 {
+			http.Error(w, "The selected time slot is unavailable due to a conflict.", http.StatusConflict)
+			return
+		}
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":          status,
+		"decided_by":      claims.UserID,
+		"decided_at":      now,
+		"decision_reason": payload.Reason,
+	}}
+
+	// Filtering on the current status makes the transition atomic: if another
+	// admin changed the reservation in the meantime, nothing is updated.
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": reservation.ID, "status": reservation.Status}, update)
+	if err != nil {
+		log.Printf("ERROR: Failed to update reservation %s: %v", reservation.ID.Hex(), err)
+		http.Error(w, "Failed to update reservation", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Reservation was modified by another request, please retry", http.StatusConflict)
+		return
+	}
+
+	reservation.Status = status
+	reservation.DecidedBy = &claims.UserID
+	reservation.DecidedAt = &now
+	reservation.DecisionReason = payload.Reason
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// roomLockTTL bounds how long a crashed request can keep a room locked. It is
+// longer than the 5 second timeout used for the work done under the lock.
+const roomLockTTL = 10 * time.Second
+
+// lockRoom serialises booking writes for a room. Every code path that checks
+// for conflicts and then creates or approves a reservation, that schedules
+// maintenance, or that removes the room, must hold it.
+func lockRoom(ctx context.Context, db *mongo.Database, roomID primitive.ObjectID) (*database.Lock, error) {
+	return database.AcquireLock(ctx, db, "room:"+roomID.Hex(), roomLockTTL)
+}
+
+// lockBooking takes the room lock and then a lock on the user's bookings, so
+// that the booking policy's active reservation limit, which spans all rooms,
+// holds under concurrent requests. Paths creating or moving a user's
+// reservations use it in place of lockRoom. The returned func releases both.
+func lockBooking(ctx context.Context, db *mongo.Database, roomID, userID primitive.ObjectID) (func(), error) {
+	roomLock, err := lockRoom(ctx, db, roomID)
+	if err != nil {
+		return nil, err
+	}
+	userLock, err := database.AcquireLock(ctx, db, "bookings:"+userID.Hex(), roomLockTTL)
+	if err != nil {
+		roomLock.Release(context.Background())
+		return nil, err
+	}
+	return func() {
+		userLock.Release(context.Background())
+		roomLock.Release(context.Background())
+	}, nil
+}
+
+// findConflicts checks each interval against the approved reservations,
+// blackouts and maintenance windows affecting the room and returns a conflict, keyed by the interval's
+// index, for every interval that cannot be booked. Reservations listed in
+// exclude are ignored. Callers must hold the room lock when the result guards a write.
+func (h *ReservationHandler) findConflicts(ctx context.Context, roomID primitive.ObjectID, intervals []models.TimeInterval, exclude ...primitive.ObjectID) (map[int]models.ReservationConflict, error) {
+	conflicts := map[int]models.ReservationConflict{}
+	if len(intervals) == 0 {
+		return conflicts, nil
+	}
+
+	var room models.Room
+	err := h.db.Collection("rooms").FindOne(ctx, bson.M{"_id": roomID, "deleted_at": nil}).Decode(&room)
+	if err == mongo.ErrNoDocuments {
+		return nil, errRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch everything approved within the overall span once, then match in
+	// memory, so a semester-long series costs a single query.
+	span := intervals[0]
+	for _, interval := range intervals[1:] {
+		if interval.Start.Before(span.Start) {
+			span.Start = interval.Start
+		}
+		if interval.End.After(span.End) {
+			span.End = interval.End
+		}
+	}
+
+	filter := overlapFilter(roomID, span.Start, span.End)
+	filter["status"] = models.ReservationApproved
+	if len(exclude) > 0 {
+		filter["_id"] = bson.M{"$nin": exclude}
+	}
+
+	cursor, err := h.db.Collection("reservations").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var approved []models.Reservation
+	if err = cursor.All(ctx, &approved); err != nil {
+		return nil, err
+	}
+
+	blackouts, err := findBlackouts(ctx, h.db, room, span.Start, span.End)
+	if err != nil {
+		return nil, err
+	}
+	maintenance, err := findMaintenance(ctx, h.db, room.ID, span.Start, span.End)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, interval := range intervals {
+		for _, blackout := range blackouts {
+			if blackout.Start.Before(interval.End) && blackout.End.After(interval.Start) {
+				conflicts[i] = models.ReservationConflict{
+					StartTime:  interval.Start,
+					EndTime:    interval.End,
+					BlackoutID: blackout.BlackoutID,
+					Reason:     "falls within a blackout period: " + blackout.Title,
+				}
+				break
+			}
+		}
+		if _, found := conflicts[i]; found {
+			continue
+		}
+		for _, window := range maintenance {
+			if window.Start.Before(interval.End) && window.End.After(interval.Start) {
+				conflicts[i] = models.ReservationConflict{
+					StartTime: interval.Start,
+					EndTime:   interval.End,
+					Reason:    "the room is under maintenance: " + window.Title,
+				}
+				break
+			}
+		}
+		if _, found := conflicts[i]; found {
+			continue
+		}
+		for _, reservation := range approved {
+			if reservation.StartTime.Before(interval.End) && reservation.EndTime.After(interval.Start) {
+				id := reservation.ID
+				conflicts[i] = models.ReservationConflict{
+					StartTime:     interval.Start,
+					EndTime:       interval.End,
+					ReservationID: &id,
+					Reason:        "overlaps an approved reservation",
+				}
+				break
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// errRoomNotFound is returned by checkPolicy and findConflicts when the room does not exist.
+var errRoomNotFound = errors.New("room not found")
+
+// checkPolicy evaluates the booking policy that applies to the caller's role and
+// the room's type against every interval of one booking: a single reservation
+// or a whole series, identified by booking. A series counts once towards the
+// active reservation limit, and only its first occurrence has to fall within
+// the advance window. Reservations listed in exclude are being replaced and
+// are not counted. Callers must hold the locks taken by lockBooking when the
+// result guards a write.
+func (h *ReservationHandler) checkPolicy(ctx context.Context, claims *auth.Claims, roomID, booking primitive.ObjectID, intervals []models.TimeInterval, exclude ...primitive.ObjectID) ([]policy.Violation, error) {
+	var room models.Room
+	err := h.db.Collection("rooms").FindOne(ctx, bson.M{"_id": roomID, "deleted_at": nil}).Decode(&room)
+	if err == mongo.ErrNoDocuments {
+		return nil, errRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := h.db.Collection("booking_policies").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var policies []models.BookingPolicy
+	if err = cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+
+	applicable, found := policy.Select(policies, room.Type, claims.Role)
+	if !found {
+		return nil, nil
+	}
+
+	now := time.Now()
+	activeFilter := bson.M{
+		"user_id":  claims.UserID,
+		"status":   bson.M{"$in": []string{models.ReservationPending, models.ReservationApproved}},
+		"end_time": bson.M{"$gt": now},
+	}
+	if len(exclude) > 0 {
+		activeFilter["_id"] = bson.M{"$nin": exclude}
+	}
+	cursor, err = h.db.Collection("reservations").Find(ctx, activeFilter, options.Find().SetProjection(bson.M{"_id": 1, "series_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var active []models.Reservation
+	if err = cursor.All(ctx, &active); err != nil {
+		return nil, err
+	}
+	bookings := map[primitive.ObjectID]bool{booking: true}
+	for _, reservation := range active {
+		if reservation.SeriesID != nil {
+			bookings[*reservation.SeriesID] = true
+		} else {
+			bookings[reservation.ID] = true
+		}
+	}
+
+	first := 0
+	for i, interval := range intervals {
+		if interval.Start.Before(intervals[first].Start) {
+			first = i
+		}
+	}
+
+	var violations []policy.Violation
+	for i, interval := range intervals {
+		request := policy.Request{
+			Start:              interval.Start,
+			End:                interval.End,
+			Now:                now,
+			ActiveReservations: len(bookings),
+		}
+		for _, violation := range policy.Evaluate(applicable, request, department.Location) {
+			// These limits concern the booking as a whole, so they are only
+			// checked, and reported once, for its first occurrence.
+			if (violation.Code == policy.CodeActiveLimitReached || violation.Code == policy.CodeMaxAdvance) && i != first {
+				continue
+			}
+			if len(intervals) > 1 {
+				start := interval.Start
+				violation.StartTime = &start
+			}
+			violations = append(violations, violation)
+		}
+	}
+	return violations, nil
+}
+
+// writeBookingCheckError reports a failure to evaluate the booking policy or
+// to check for conflicts.
+func writeBookingCheckError(w http.ResponseWriter, err error) {
+	if err == errRoomNotFound {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("ERROR: Failed to check booking: %v", err)
+	http.Error(w, "Failed to check for booking conflicts", http.StatusInternalServerError)
+}
+
+// writePolicyViolations responds with the machine-readable list of violations.
+func writePolicyViolations(w http.ResponseWriter, violations []policy.Violation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "The reservation violates the booking policy.",
+		"violations": violations,
+	})
+}
+
+// overlapFilter matches reservations in the given room whose time range
+// intersects [start, end).
+func overlapFilter(roomID primitive.ObjectID, start, end time.Time) bson.M {
+	return bson.M{
+		"room_id":    roomID,
+		"start_time": bson.M{"$lt": end},
+		"end_time":   bson.M{"$gt": start},
+	}
+}
+
+// parseTimeParam parses a query parameter given either as RFC3339 or as a plain
+// date, which is taken as midnight in the department's time zone.
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, department.Location)
+}
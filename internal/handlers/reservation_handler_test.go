@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/policy"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestApproveReservationAllowsOneOfOverlappingRequests(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	room := models.Room{ID: primitive.NewObjectID(), RoomID: "R-RACE", Name: "Race", Type: "classroom"}
+	if _, err := db.Collection("rooms").InsertOne(ctx, room); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every reservation overlaps the first by half an hour.
+	const n = 20
+	start := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Hour)
+	ids := make([]primitive.ObjectID, n)
+	for i := range ids {
+		reservation := models.Reservation{
+			ID:        primitive.NewObjectID(),
+			RoomID:    room.ID,
+			UserID:    primitive.NewObjectID(),
+			Purpose:   "race",
+			StartTime: start.Add(time.Duration(i%2) * 30 * time.Minute),
+			EndTime:   start.Add(time.Hour),
+			Status:    models.ReservationPending,
+			CreatedAt: time.Now(),
+		}
+		if _, err := db.Collection("reservations").InsertOne(ctx, reservation); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = reservation.ID
+	}
+
+	h := NewReservationHandler(db)
+	claims := &auth.Claims{UserID: primitive.NewObjectID(), Role: auth.RoleAdmin}
+
+	var wg sync.WaitGroup
+	codes := make([]int, n)
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/reservations/"+id.Hex()+"/approve", nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.ClaimsKey, claims))
+			req = mux.SetURLVars(req, map[string]string{"id": id.Hex()})
+			rec := httptest.NewRecorder()
+			h.ApproveReservation(rec, req)
+			codes[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	approved := 0
+	for i, code := range codes {
+		switch code {
+		case http.StatusOK:
+			approved++
+		case http.StatusConflict:
+		default:
+			t.Errorf("approving reservation %d: status %d", i, code)
+		}
+	}
+	if approved != 1 {
+		t.Errorf("%d overlapping reservations approved, want 1", approved)
+	}
+
+	count, err := db.Collection("reservations").CountDocuments(ctx, bson.M{"status": models.ReservationApproved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("%d reservations stored as approved, want 1", count)
+	}
+}
+
+func TestCreateReservationEnforcesActiveLimitUnderConcurrentRequests(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	bookingPolicy := models.BookingPolicy{
+		ID:                    primitive.NewObjectID(),
+		RoomType:              models.PolicyWildcard,
+		Role:                  models.PolicyWildcard,
+		AllowBooking:          true,
+		MaxActiveReservations: 1,
+	}
+	if _, err := db.Collection("booking_policies").InsertOne(ctx, bookingPolicy); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same student books a different room from each request, so only the
+	// per-user lock keeps them from all passing the limit check.
+	const n = 10
+	rooms := make([]primitive.ObjectID, n)
+	for i := range rooms {
+		room := models.Room{ID: primitive.NewObjectID(), RoomID: fmt.Sprintf("R-LIMIT-%d", i), Name: "Limit", Type: "classroom"}
+		if _, err := db.Collection("rooms").InsertOne(ctx, room); err != nil {
+			t.Fatal(err)
+		}
+		rooms[i] = room.ID
+	}
+
+	h := NewReservationHandler(db)
+	claims := &auth.Claims{UserID: primitive.NewObjectID(), Role: auth.RoleStudent}
+	start := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Hour)
+
+	var wg sync.WaitGroup
+	codes := make([]int, n)
+	bodies := make([]string, n)
+	for i, roomID := range rooms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"roomId":%q,"purpose":"race","startTime":%q,"endTime":%q}`,
+				roomID.Hex(), start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+			req := httptest.NewRequest(http.MethodPost, "/api/reservations", strings.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.ClaimsKey, claims))
+			rec := httptest.NewRecorder()
+			h.CreateReservation(rec, req)
+			codes[i], bodies[i] = rec.Code, rec.Body.String()
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for i, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusUnprocessableEntity:
+			if !strings.Contains(bodies[i], policy.CodeActiveLimitReached) {
+				t.Errorf("request %d: got violations %s, want %s", i, bodies[i], policy.CodeActiveLimitReached)
+			}
+		default:
+			t.Errorf("request %d: status %d (%s)", i, code, bodies[i])
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d reservations created, want 1", created)
+	}
+
+	count, err := db.Collection("reservations").CountDocuments(ctx, bson.M{"user_id": claims.UserID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("%d reservations stored, want 1", count)
+	}
+}
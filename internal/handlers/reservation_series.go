@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/recurrence"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateReservationSeries expands a recurrence rule into one Pending reservation
+// per occurrence. Every occurrence is checked for conflicts; unless
+// skipConflicts is set, any conflict rejects the whole series and is reported
+// per occurrence.
+func (h *ReservationHandler) CreateReservationSeries(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.CreateReservationSeriesPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// --- Validation ---
+	roomObjID, err := primitive.ObjectIDFromHex(payload.RoomID)
+	if err != nil {
+		http.Error(w, "Invalid Room ID format", http.StatusBadRequest)
+		return
+	}
+	startTime, err := time.Parse(time.RFC3339, payload.StartTime)
+	if err != nil {
+		http.Error(w, "Invalid Start Time format", http.StatusBadRequest)
+		return
+	}
+	endTime, err := time.Parse(time.RFC3339, payload.EndTime)
+	if err != nil {
+		http.Error(w, "Invalid End Time format", http.StatusBadRequest)
+		return
+	}
+	if !endTime.After(startTime) {
+		http.Error(w, "End time must be after start time", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := recurrence.Parse(payload.Recurrence, department.Location)
+	if err != nil {
+		http.Error(w, "Invalid recurrence rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	exDates := make([]time.Time, 0, len(payload.ExDates))
+	for _, value := range payload.ExDates {
+		day, err := time.ParseInLocation("2006-01-02", value, department.Location)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid exception date %q", value), http.StatusBadRequest)
+			return
+		}
+		exDates = append(exDates, day)
+	}
+
+	// Expand in local time so weekdays and wall-clock times follow the department's calendar.
+	starts, err := rule.Occurrences(startTime.In(department.Location), exDates)
+	if err != nil {
+		http.Error(w, "Invalid recurrence rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(starts) == 0 {
+		http.Error(w, "The recurrence rule produces no occurrences", http.StatusBadRequest)
+		return
+	}
+
+	duration := endTime.Sub(startTime)
+	intervals := make([]models.TimeInterval, len(starts))
+	for i, start := range starts {
+		intervals[i] = models.TimeInterval{Start: start, End: start.Add(duration)}
+	}
+
+	// --- Conflict Check ---
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	release, err := lockBooking(ctx, h.db, roomObjID, claims.UserID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock room %s: %v", roomObjID.Hex(), err)
+		http.Error(w, "The room is busy, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	seriesID := primitive.NewObjectID()
+	violations, err := h.checkPolicy(ctx, claims, roomObjID, seriesID, intervals)
+	if err != nil {
+		writeBookingCheckError(w, err)
+		return
+	}
+	if len(violations) > 0 {
+		writePolicyViolations(w, violations)
+		return
+	}
+
+	conflictsByIndex, err := h.findConflicts(ctx, roomObjID, intervals)
+	if err != nil {
+		writeBookingCheckError(w, err)
+		return
+	}
+	conflicts := []models.ReservationConflict{}
+	for i := range intervals {
+		if conflict, found := conflictsByIndex[i]; found {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	if len(conflicts) == len(intervals) || (len(conflicts) > 0 && !payload.SkipConflicts) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":   "Some occurrences are unavailable due to a conflict.",
+			"conflicts": conflicts,
+		})
+		return
+	}
+
+	// --- Create Series ---
+	now := time.Now()
+	series := models.ReservationSeries{
+		ID:          seriesID,
+		RoomID:      roomObjID,
+		UserID:      claims.UserID,
+		Purpose:     payload.Purpose,
+		Description: payload.Description,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Recurrence:  payload.Recurrence,
+		ExDates:     exDates,
+		CreatedAt:   now,
+	}
+
+	var occurrences []interface{}
+	reservationIDs := []string{}
+	for i, interval := range intervals {
+		if _, conflicting := conflictsByIndex[i]; conflicting {
+			continue
+		}
+		reservation := models.Reservation{
+			ID:          primitive.NewObjectID(),
+			RoomID:      roomObjID,
+			UserID:      claims.UserID,
+			Purpose:     payload.Purpose,
+			Description: payload.Description,
+			StartTime:   interval.Start,
+			EndTime:     interval.End,
+			Status:      models.ReservationPending,
+			CreatedAt:   now,
+			SeriesID:    &series.ID,
+		}
+		occurrences = append(occurrences, reservation)
+		reservationIDs = append(reservationIDs, reservation.ID.Hex())
+	}
+
+	if _, err := h.db.Collection("reservation_series").InsertOne(ctx, series); err != nil {
+		log.Printf("ERROR: Failed to insert reservation series: %v", err)
+		http.Error(w, "Failed to create reservation series", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.db.Collection("reservations").InsertMany(ctx, occurrences); err != nil {
+		log.Printf("ERROR: Failed to insert occurrences of series %s: %v", series.ID.Hex(), err)
+		http.Error(w, "Failed to create reservation series", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("SUCCESS: Reservation series %s created with %d occurrences", series.ID.Hex(), len(occurrences))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Reservasi berulang berhasil dibuat",
+		"seriesId":       series.ID.Hex(),
+		"reservationIds": reservationIDs,
+		"conflicts":      conflicts,
+	})
+}
+
+// GetReservationSeries returns a series with all of its occurrences. Only the
+// owner and admins can see it.
+func (h *ReservationHandler) GetReservationSeries(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	series, ok := h.findSeries(w, r, claims, true)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "start_time", Value: 1}})
+
+	cursor, err := h.db.Collection("reservations").Find(ctx, bson.M{"series_id": series.ID}, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	result := models.ReservationSeriesWithOccurrences{ReservationSeries: series}
+	if err = cursor.All(ctx, &result.Occurrences); err != nil {
+		http.Error(w, "Failed to parse reservations data", http.StatusInternalServerError)
+		return
+	}
+	if result.Occurrences == nil {
+		result.Occurrences = []models.Reservation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// UpdateReservationSeries edits every upcoming Pending or Approved occurrence of
+// the caller's series. A new time of day is checked for conflicts across all
+// occurrences and sends them back to Pending. Without a new end time each
+// occurrence keeps its duration, so bookings spanning several days stay intact.
+func (h *ReservationHandler) UpdateReservationSeries(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	series, ok := h.findSeries(w, r, claims, false)
+	if !ok {
+		return
+	}
+
+	var payload models.UpdateReservationSeriesPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	changeTime := payload.StartTime != "" || payload.EndTime != ""
+	var startOfDay time.Duration
+	var endOfDay *time.Duration
+	if changeTime {
+		start, err := time.Parse("15:04", payload.StartTime)
+		if err != nil {
+			http.Error(w, "startTime must be given as HH:MM", http.StatusBadRequest)
+			return
+		}
+		startOfDay = timeOfDay(start)
+		if payload.EndTime != "" {
+			end, err := time.Parse("15:04", payload.EndTime)
+			if err != nil {
+				http.Error(w, "endTime must be given as HH:MM", http.StatusBadRequest)
+				return
+			}
+			d := timeOfDay(end)
+			if d == startOfDay {
+				http.Error(w, "End time must be after start time", http.StatusBadRequest)
+				return
+			}
+			endOfDay = &d
+		}
+	}
+	if !changeTime && payload.Purpose == "" && payload.Description == "" {
+		http.Error(w, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	collection := h.db.Collection("reservations")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	upcoming := bson.M{
+		"series_id":  series.ID,
+		"status":     bson.M{"$in": []string{models.ReservationPending, models.ReservationApproved}},
+		"start_time": bson.M{"$gt": now},
+	}
+
+	set := bson.M{}
+	if payload.Purpose != "" {
+		set["purpose"] = payload.Purpose
+	}
+	if payload.Description != "" {
+		set["description"] = payload.Description
+	}
+
+	if changeTime {
+		release, err := lockBooking(ctx, h.db, series.RoomID, claims.UserID)
+		if err != nil {
+			log.Printf("ERROR: Failed to lock room %s: %v", series.RoomID.Hex(), err)
+			http.Error(w, "The room is busy, please try again", http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
+		cursor, err := collection.Find(ctx, upcoming)
+		if err != nil {
+			http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+			return
+		}
+		var occurrences []models.Reservation
+		if err = cursor.All(ctx, &occurrences); err != nil {
+			http.Error(w, "Failed to parse reservations data", http.StatusInternalServerError)
+			return
+		}
+
+		ids := make([]primitive.ObjectID, len(occurrences))
+		intervals := make([]models.TimeInterval, len(occurrences))
+		for i, occurrence := range occurrences {
+			ids[i] = occurrence.ID
+			intervals[i] = retime(occurrence.StartTime, occurrence.EndTime, startOfDay, endOfDay)
+		}
+
+		violations, err := h.checkPolicy(ctx, claims, series.RoomID, series.ID, intervals, ids...)
+		if err != nil {
+			writeBookingCheckError(w, err)
+			return
+		}
+		if len(violations) > 0 {
+			writePolicyViolations(w, violations)
+			return
+		}
+
+		conflictsByIndex, err := h.findConflicts(ctx, series.RoomID, intervals, ids...)
+		if err != nil {
+			writeBookingCheckError(w, err)
+			return
+		}
+		if len(conflictsByIndex) > 0 {
+			conflicts := []models.ReservationConflict{}
+			for i := range intervals {
+				if conflict, found := conflictsByIndex[i]; found {
+					conflicts = append(conflicts, conflict)
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message":   "Some occurrences are unavailable due to a conflict.",
+				"conflicts": conflicts,
+			})
+			return
+		}
+
+		for i, occurrence := range occurrences {
+			occurrenceSet := bson.M{
+				"start_time": intervals[i].Start,
+				"end_time":   intervals[i].End,
+				"status":     models.ReservationPending,
+			}
+			for key, value := range set {
+				occurrenceSet[key] = value
+			}
+			update := bson.M{
+				"$set":   occurrenceSet,
+				"$unset": bson.M{"decided_by": "", "decided_at": "", "decision_reason": "", "reminder_sent_at": "", "reminder_attempts": "", "reminder_claimed_until": ""},
+			}
+			if _, err := collection.UpdateOne(ctx, bson.M{"_id": occurrence.ID}, update); err != nil {
+				log.Printf("ERROR: Failed to update occurrence %s: %v", occurrence.ID.Hex(), err)
+				http.Error(w, "Failed to update reservation series", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		first := retime(series.StartTime, series.EndTime, startOfDay, endOfDay)
+		set["start_time"] = first.Start
+		set["end_time"] = first.End
+	} else {
+		if _, err := collection.UpdateMany(ctx, upcoming, bson.M{"$set": set}); err != nil {
+			log.Printf("ERROR: Failed to update occurrences of series %s: %v", series.ID.Hex(), err)
+			http.Error(w, "Failed to update reservation series", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if _, err := h.db.Collection("reservation_series").UpdateOne(ctx, bson.M{"_id": series.ID}, bson.M{"$set": set}); err != nil {
+		log.Printf("ERROR: Failed to update reservation series %s: %v", series.ID.Hex(), err)
+		http.Error(w, "Failed to update reservation series", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":  "Reservasi berulang berhasil diperbarui",
+		"seriesId": series.ID.Hex(),
+	})
+}
+
+// timeOfDay returns the time elapsed since midnight on t's clock.
+func timeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// retime moves the booking [start, end) to startOfDay on its local start date.
+// With an endOfDay it ends at the first such time after the new start, which
+// is the next day when endOfDay is earlier than startOfDay; without one it
+// keeps its duration.
+func retime(start, end time.Time, startOfDay time.Duration, endOfDay *time.Duration) models.TimeInterval {
+	local := start.In(department.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, department.Location)
+	interval := models.TimeInterval{Start: day.Add(startOfDay)}
+	if endOfDay == nil {
+		interval.End = interval.Start.Add(end.Sub(start))
+		return interval
+	}
+	interval.End = day.Add(*endOfDay)
+	if !interval.End.After(interval.Start) {
+		interval.End = interval.End.AddDate(0, 0, 1)
+	}
+	return interval
+}
+
+// CancelReservationSeries cancels every upcoming Pending or Approved occurrence
+// of the caller's series. Past occurrences are left untouched.
+func (h *ReservationHandler) CancelReservationSeries(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	series, ok := h.findSeries(w, r, claims, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"series_id":  series.ID,
+		"status":     bson.M{"$in": []string{models.ReservationPending, models.ReservationApproved}},
+		"start_time": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"status": models.ReservationCancelled, "cancelled_at": now}}
+
+	result, err := h.db.Collection("reservations").UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Printf("ERROR: Failed to cancel series %s: %v", series.ID.Hex(), err)
+		http.Error(w, "Failed to cancel reservation series", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Reservasi berulang berhasil dibatalkan",
+		"seriesId":  series.ID.Hex(),
+		"cancelled": result.ModifiedCount,
+	})
+}
+
+// findSeries loads the series named in the URL and writes an error response if
+// it does not exist or does not belong to the caller. Admins can read any
+// series when allowAdmin is set.
+func (h *ReservationHandler) findSeries(w http.ResponseWriter, r *http.Request, claims *auth.Claims, allowAdmin bool) (models.ReservationSeries, bool) {
+	var series models.ReservationSeries
+
+	seriesID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Series ID format", http.StatusBadRequest)
+		return series, false
+	}
+
+	err = h.db.Collection("reservation_series").FindOne(context.TODO(), bson.M{"_id": seriesID}).Decode(&series)
+	if err == mongo.ErrNoDocuments || (err == nil && series.UserID != claims.UserID && !(allowAdmin && claims.Can(auth.PermViewAllReservations))) {
+		http.Error(w, "Reservation series not found", http.StatusNotFound)
+		return series, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservation series", http.StatusInternalServerError)
+		return series, false
+	}
+	return series, true
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
+)
+
+func TestRetime(t *testing.T) {
+	day := func(d, h, m int) time.Time { return time.Date(2025, 9, d, h, m, 0, 0, department.Location) }
+	hours := func(h int) *time.Duration { d := time.Duration(h) * time.Hour; return &d }
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		startOfDay time.Duration
+		endOfDay   *time.Duration
+		want       [2]time.Time
+	}{
+		{"same day", day(8, 8, 0), day(8, 10, 0), 13 * time.Hour, hours(15), [2]time.Time{day(8, 13, 0), day(8, 15, 0)}},
+		{"keeps duration", day(8, 8, 0), day(8, 10, 30), 13 * time.Hour, nil, [2]time.Time{day(8, 13, 0), day(8, 15, 30)}},
+		{"keeps several days", day(8, 8, 0), day(10, 17, 0), 7 * time.Hour, nil, [2]time.Time{day(8, 7, 0), day(10, 16, 0)}},
+		{"overnight", day(8, 8, 0), day(8, 10, 0), 22 * time.Hour, hours(2), [2]time.Time{day(8, 22, 0), day(9, 2, 0)}},
+	}
+	for _, tt := range tests {
+		got := retime(tt.start, tt.end, tt.startOfDay, tt.endOfDay)
+		if !got.Start.Equal(tt.want[0]) || !got.End.Equal(tt.want[1]) {
+			t.Errorf("%s: got %v – %v, want %v – %v", tt.name, got.Start, got.End, tt.want[0], tt.want[1])
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/notify"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxRoomCapacity = 1000
+
+var (
+	roomIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,20}$`)
+	roomTypes     = []string{"high", "medium", "low"}
+)
+
+// RoomHandler handles admin management of rooms.
+type RoomHandler struct {
+	db       *mongo.Database
+	notifier notify.Notifier
+}
+
+// NewRoomHandler creates a new RoomHandler.
+func NewRoomHandler(db *mongo.Database, notifier notify.Notifier) *RoomHandler {
+	return &RoomHandler{db: db, notifier: notifier}
+}
+
+// CreateRoom adds a new room to the catalog.
+func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
+	room, ok := decodeRoom(w, r)
+	if !ok {
+		return
+	}
+	room.ID = primitive.NewObjectID()
+	room.Status = models.RoomAvailable
+
+	_, err := h.db.Collection("rooms").InsertOne(context.TODO(), room)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Room ID already in use", http.StatusConflict)
+			return
+		}
+		log.Printf("ERROR: Failed to insert room: %v", err)
+		http.Error(w, "Failed to create room", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(room)
+}
+
+// UpdateRoom replaces the editable fields of a room.
+func (h *RoomHandler) UpdateRoom(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Room ID format", http.StatusBadRequest)
+		return
+	}
+
+	room, ok := decodeRoom(w, r)
+	if !ok {
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"room_id":   room.RoomID,
+		"name":      room.Name,
+		"image_url": room.ImageURL,
+		"capacity":  room.Capacity,
+		"location":  room.Location,
+		"type":      room.Type,
+		"facility":  room.Facility,
+	}}
+	findOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var saved models.Room
+	err = h.db.Collection("rooms").FindOneAndUpdate(context.TODO(), bson.M{"_id": objID, "deleted_at": nil}, update, findOptions).Decode(&saved)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Room ID already in use", http.StatusConflict)
+			return
+		}
+		log.Printf("ERROR: Failed to update room %s: %v", objID.Hex(), err)
+		http.Error(w, "Failed to update room", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteRoom soft-deletes a room. Deletion is refused while the room has
+// upcoming approved reservations, unless cascade=true is passed, in which case
+// those reservations are cancelled and their owners notified. Upcoming pending
+// reservations are always cancelled, since they can no longer be approved.
+// A room cannot be deleted while an approved reservation is in progress.
+func (h *RoomHandler) DeleteRoom(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Room ID format", http.StatusBadRequest)
+		return
+	}
+	cascade := r.URL.Query().Get("cascade") == "true"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var room models.Room
+	err = h.db.Collection("rooms").FindOne(ctx, bson.M{"_id": objID, "deleted_at": nil}).Decode(&room)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve room data", http.StatusInternalServerError)
+		return
+	}
+
+	// Hold the room lock so nothing is booked or approved while deleting.
+	lock, err := lockRoom(ctx, h.db, room.ID)
+	if err != nil {
+		log.Printf("ERROR: Failed to lock room %s: %v", room.ID.Hex(), err)
+		http.Error(w, "The room is busy, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	defer lock.Release(context.Background())
+
+	now := time.Now()
+	reservations := h.db.Collection("reservations")
+	var running models.Reservation
+	err = reservations.FindOne(ctx, bson.M{
+		"room_id":    room.ID,
+		"status":     models.ReservationApproved,
+		"start_time": bson.M{"$lte": now},
+		"end_time":   bson.M{"$gt": now},
+	}).Decode(&running)
+	if err == nil {
+		http.Error(w, fmt.Sprintf("The room is in use until %s", running.EndTime.In(department.Location).Format("02 Jan 2006 15:04")), http.StatusConflict)
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+
+	upcoming := bson.M{
+		"room_id":    room.ID,
+		"status":     bson.M{"$in": []string{models.ReservationPending, models.ReservationApproved}},
+		"start_time": bson.M{"$gt": now},
+	}
+	cursor, err := reservations.Find(ctx, upcoming)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reservations", http.StatusInternalServerError)
+		return
+	}
+	var affected []models.Reservation
+	if err = cursor.All(ctx, &affected); err != nil {
+		http.Error(w, "Failed to parse reservations data", http.StatusInternalServerError)
+		return
+	}
+
+	approved := []models.Reservation{}
+	for _, reservation := range affected {
+		if reservation.Status == models.ReservationApproved {
+			approved = append(approved, reservation)
+		}
+	}
+	if len(approved) > 0 && !cascade {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":      "The room has upcoming approved reservations. Pass cascade=true to cancel them.",
+			"reservations": approved,
+		})
+		return
+	}
+
+	reason := fmt.Sprintf("Ruangan %s tidak lagi tersedia", room.Name)
+	if len(affected) > 0 {
+		update := bson.M{"$set": bson.M{
+			"status":          models.ReservationCancelled,
+			"cancelled_at":    now,
+			"decided_by":      claims.UserID,
+			"decided_at":      now,
+			"decision_reason": reason,
+		}}
+		if _, err := reservations.UpdateMany(ctx, upcoming, update); err != nil {
+			log.Printf("ERROR: Failed to cancel reservations of room %s: %v", room.ID.Hex(), err)
+			http.Error(w, "Failed to cancel reservations", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if _, err := h.db.Collection("rooms").UpdateOne(ctx, bson.M{"_id": room.ID}, bson.M{"$set": bson.M{"deleted_at": now}}); err != nil {
+		log.Printf("ERROR: Failed to delete room %s: %v", room.ID.Hex(), err)
+		http.Error(w, "Failed to delete room", http.StatusInternalServerError)
+		return
+	}
+
+	for _, reservation := range affected {
+		id := reservation.ID
+		notification := models.Notification{
+			UserID: reservation.UserID,
+			Type:   models.NotificationReservationCancelled,
+			Title:  "Reservasi dibatalkan",
+			Message: fmt.Sprintf("Reservasi Anda untuk %s pada %s dibatalkan. %s.",
+				room.Name, reservation.StartTime.In(department.Location).Format("02 Jan 2006 15:04"), reason),
+			RefID: &id,
+		}
+		if err := h.notifier.Notify(ctx, notification); err != nil {
+			log.Printf("Failed to notify user %s about reservation %s: %v", reservation.UserID.Hex(), id.Hex(), err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Room deleted",
+		"cancelled": len(affected),
+	})
+}
+
+// decodeRoom parses and validates a RoomPayload, writing an error response and
+// returning false if it is invalid.
+func decodeRoom(w http.ResponseWriter, r *http.Request) (models.Room, bool) {
+	var payload models.RoomPayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return models.Room{}, false
+	}
+
+	room := models.Room{
+		RoomID:   strings.TrimSpace(payload.RoomID),
+		Name:     strings.TrimSpace(payload.Name),
+		ImageURL: strings.TrimSpace(payload.ImageURL),
+		Capacity: payload.Capacity,
+		Location: strings.TrimSpace(payload.Location),
+		Type:     payload.Type,
+	}
+
+	switch {
+	case !roomIDPattern.MatchString(room.RoomID):
+		http.Error(w, "room_id must be 1-20 letters, digits, '-' or '_'", http.StatusBadRequest)
+	case room.Name == "":
+		http.Error(w, "Name is required", http.StatusBadRequest)
+	case room.Location == "":
+		http.Error(w, "Location is required", http.StatusBadRequest)
+	case room.Capacity < 1 || room.Capacity > maxRoomCapacity:
+		http.Error(w, fmt.Sprintf("Capacity must be between 1 and %d", maxRoomCapacity), http.StatusBadRequest)
+	case !slices.Contains(roomTypes, room.Type):
+		http.Error(w, "Type must be one of high, medium or low", http.StatusBadRequest)
+	case payload.Facility == nil:
+		http.Error(w, "Facility is required", http.StatusBadRequest)
+	default:
+		room.Facility = *payload.Facility
+		return room, true
+	}
+	return room, false
+}
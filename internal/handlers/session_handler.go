@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionHandler lets users manage their own login sessions and admins revoke
+// the sessions of any account.
+type SessionHandler struct {
+	db       *mongo.Database
+	sessions *sessions.Store
+	cookies  authCookies
+}
+
+// NewSessionHandler creates a new SessionHandler.
+func NewSessionHandler(db *mongo.Database, sessionStore *sessions.Store, cfg config.Config) *SessionHandler {
+	return &SessionHandler{db: db, sessions: sessionStore, cookies: newAuthCookies(cfg)}
+}
+
+// GetMySessions lists the caller's active sessions, marking the one making
+// the request as current.
+func (h *SessionHandler) GetMySessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	list, err := h.sessions.ListActive(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionInfos(list, claims.SessionID))
+}
+
+// RevokeMySession ends one of the caller's sessions. Revoking the current
+// session also clears the authentication cookies.
+func (h *SessionHandler) RevokeMySession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid Session ID format", http.StatusBadRequest)
+		return
+	}
+
+	err = h.sessions.RevokeOwned(r.Context(), claims.UserID, sessionID, models.SessionRevokedByUser)
+	if errors.Is(err, sessions.ErrSessionRevoked) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	if sessionID == claims.SessionID {
+		h.cookies.clear(w)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked"})
+}
+
+// RevokeAllMySessions logs the caller out everywhere. With keepCurrent=true
+// the session making the request stays signed in.
+func (h *SessionHandler) RevokeAllMySessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	keepCurrent := r.URL.Query().Get("keepCurrent") == "true"
+	var except []primitive.ObjectID
+	if keepCurrent {
+		except = append(except, claims.SessionID)
+	}
+
+	revoked, err := h.sessions.RevokeAll(r.Context(), claims.UserID, models.SessionRevokedLogoutAll, except...)
+	if err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	if !keepCurrent {
+		h.cookies.clear(w)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Sessions revoked",
+		"revoked": revoked,
+	})
+}
+
+// GetUserSessions lists the active sessions of an account no more privileged
+// than the caller.
+func (h *SessionHandler) GetUserSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	userID, ok := h.userFromPath(w, r, claims)
+	if !ok {
+		return
+	}
+
+	list, err := h.sessions.ListActive(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionInfos(list, primitive.NilObjectID))
+}
+
+// RevokeUserSessions force-revokes every session of an account, for example
+// after a stolen device or a compromised password. Admins cannot revoke the
+// sessions of someone more privileged.
+func (h *SessionHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	userID, ok := h.userFromPath(w, r, claims)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.sessions.RevokeAll(r.Context(), userID, models.SessionRevokedByAdmin)
+	if err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Admin %s revoked %d session(s) of user %s", claims.UserID.Hex(), revoked, userID.Hex())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Sessions revoked",
+		"revoked": revoked,
+	})
+}
+
+// userFromPath parses the {id} path variable and checks the user exists and
+// is no more privileged than the caller, writing the error response when not.
+func (h *SessionHandler) userFromPath(w http.ResponseWriter, r *http.Request, claims *auth.Claims) (primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid User ID format", http.StatusBadRequest)
+		return primitive.NilObjectID, false
+	}
+
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"role": 1})
+	err = h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return primitive.NilObjectID, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return primitive.NilObjectID, false
+	}
+	if !auth.HasRole(claims.Role, user.Role) {
+		http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+// sessionInfos converts sessions to their public form.
+func sessionInfos(list []models.Session, current primitive.ObjectID) []models.SessionInfo {
+	infos := make([]models.SessionInfo, 0, len(list))
+	for _, s := range list {
+		infos = append(infos, models.SessionInfo{
+			ID:         s.ID,
+			Device:     sessions.Device(s.UserAgent),
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    !current.IsZero() && s.ID == current,
+		})
+	}
+	return infos
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAdminCannotManageSuperAdminSessions(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	superadmin := models.User{ID: primitive.NewObjectID(), Email: "superadmin@unsrat.ac.id", Role: auth.RoleSuperAdmin}
+	student := models.User{ID: primitive.NewObjectID(), Email: "student@unsrat.ac.id", Role: auth.RoleStudent}
+	for _, user := range []models.User{superadmin, student} {
+		if _, err := db.Collection("users").InsertOne(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := sessions.NewStore(db, time.Hour)
+	h := NewSessionHandler(db, store, config.Config{})
+
+	tests := []struct {
+		name   string
+		target models.User
+		list   bool
+		want   int
+	}{
+		{"list superadmin sessions", superadmin, true, http.StatusForbidden},
+		{"revoke superadmin sessions", superadmin, false, http.StatusForbidden},
+		{"list student sessions", student, true, http.StatusOK},
+		{"revoke student sessions", student, false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, _, err := store.Create(ctx, tt.target.ID, "test", "127.0.0.1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims := &auth.Claims{UserID: primitive.NewObjectID(), Role: auth.RoleAdmin}
+			method, path, handle := http.MethodPost, "/api/admin/users/"+tt.target.ID.Hex()+"/sessions/revoke", h.RevokeUserSessions
+			if tt.list {
+				method, path, handle = http.MethodGet, "/api/admin/users/"+tt.target.ID.Hex()+"/sessions", h.GetUserSessions
+			}
+			req := httptest.NewRequest(method, path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.ClaimsKey, claims))
+			req = mux.SetURLVars(req, map[string]string{"id": tt.target.ID.Hex()})
+			rec := httptest.NewRecorder()
+			handle(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status: got %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.want == http.StatusForbidden {
+				if err := store.ValidateSession(ctx, session.ID); err != nil {
+					t.Errorf("refused request revoked the session: %v", err)
+				}
+			}
+		})
+	}
+}
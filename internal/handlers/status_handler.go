@@ -21,10 +21,10 @@ func NewStatusHandler(db *mongo.Database) *StatusHandler {
 	return &StatusHandler{db: db}
 }
 
-// GetRooms fetches all rooms from the database.
+// GetRooms fetches all rooms from the database with their current status.
 func (h *StatusHandler) GetRooms(w http.ResponseWriter, r *http.Request) {
 	roomsCollection := h.db.Collection("rooms")
-	cursor, err := roomsCollection.Find(context.TODO(), bson.D{})
+	cursor, err := roomsCollection.Find(context.TODO(), bson.M{"deleted_at": nil})
 	if err != nil {
 		http.Error(w, "Failed to retrieve rooms", http.StatusInternalServerError)
 		return
@@ -37,6 +37,11 @@ func (h *StatusHandler) GetRooms(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = deriveRoomStatuses(context.TODO(), h.db, rooms); err != nil {
+		http.Error(w, "Failed to determine room status", http.StatusInternalServerError)
+		return
+	}
+
 	if rooms == nil {
 		rooms = []models.Room{}
 	}
@@ -51,6 +56,17 @@ func (h *StatusHandler) GetInventoryRequests(w http.ResponseWriter, r *http.Requ
 
 	findOptions := options.Find()
 	findOptions.SetSort(bson.D{{Key: "request_date", Value: -1}}) // Sort by most recent request
+	// The status page is public, so loan details such as the purpose and
+	// handover notes are left out.
+	findOptions.SetProjection(bson.M{
+		"request_id":     1,
+		"requester_name": 1,
+		"item_name":      1,
+		"quantity":       1,
+		"request_date":   1,
+		"status":         1,
+		"pickup_date":    1,
+	})
 
 	cursor, err := inventoryCollection.Find(context.TODO(), bson.D{}, findOptions)
 	if err != nil {
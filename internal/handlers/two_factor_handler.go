@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/throttle"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/totp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recoveryCodeCount is how many single-use recovery codes a user gets.
+const recoveryCodeCount = 10
+
+// TwoFactorHandler handles enrolling in and managing TOTP two-factor authentication.
+type TwoFactorHandler struct {
+	db       *mongo.Database
+	sessions *sessions.Store
+	tokens   *auth.Tokens
+	throttle *throttle.Store
+	issuer   string
+}
+
+// NewTwoFactorHandler creates a new TwoFactorHandler. cfg.TOTPIssuer is the
+// name authenticator apps show next to the account.
+func NewTwoFactorHandler(db *mongo.Database, sessionStore *sessions.Store, tokens *auth.Tokens, cfg config.Config) *TwoFactorHandler {
+	return &TwoFactorHandler{db: db, sessions: sessionStore, tokens: tokens, throttle: throttle.NewStore(db), issuer: cfg.TOTPIssuer}
+}
+
+// Enroll starts enrollment by generating a secret. The secret is not active
+// until it is confirmed with ConfirmEnrollment.
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.db.Collection("users").UpdateOne(context.TODO(),
+		bson.M{"_id": claims.UserID, "totp_enabled": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"totp_pending_secret": secret}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":     secret,
+		"otpauthUri": totp.URI(h.issuer, claims.Email, secret),
+	})
+}
+
+// ConfirmEnrollment enables two-factor authentication once the user enters a
+// code generated from the pending secret, and returns the recovery codes.
+// The current session keeps its password-only amr: enrolling from a stolen
+// session must not unlock privileged routes, so the user signs in again with
+// a code to get a two-factor session.
+func (h *TwoFactorHandler) ConfirmEnrollment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.TwoFactorCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": claims.UserID}).Decode(&user); err != nil {
+		http.Error(w, "User not found or invalid", http.StatusUnauthorized)
+		return
+	}
+	if user.TOTPEnabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if user.TOTPPendingSecret == "" {
+		http.Error(w, "Start enrollment first", http.StatusBadRequest)
+		return
+	}
+
+	step, valid := totp.Validate(user.TOTPPendingSecret, payload.Code, time.Now())
+	if !valid {
+		http.Error(w, "Invalid two-factor code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Only confirm the secret the code was checked against, in case
+	// enrollment was restarted in the meantime.
+	result, err := h.db.Collection("users").UpdateOne(context.TODO(),
+		bson.M{"_id": user.ID, "totp_pending_secret": user.TOTPPendingSecret},
+		bson.M{
+			"$set": bson.M{
+				"totp_enabled":         true,
+				"totp_secret":          user.TOTPPendingSecret,
+				"totp_last_step":       step,
+				"recovery_code_hashes": hashes,
+			},
+			"$unset": bson.M{"totp_pending_secret": ""},
+		},
+	)
+	if err != nil {
+		http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Enrollment was restarted, scan the new secret", http.StatusConflict)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":       "Two-factor authentication enabled. Store the recovery codes somewhere safe, then sign in again with a code.",
+		"recoveryCodes": codes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Disable turns two-factor authentication off. It needs the password and a
+// code, and is refused for roles that must use two-factor authentication.
+// Wrong passwords and codes count against the account's login throttle.
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.DisableTwoFactorPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": claims.UserID}).Decode(&user); err != nil {
+		http.Error(w, "User not found or invalid", http.StatusUnauthorized)
+		return
+	}
+	if !user.TOTPEnabled {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+	if h.tokens.TwoFactorRequired(user.Role) {
+		http.Error(w, "Two-factor authentication is required for your role", http.StatusForbidden)
+		return
+	}
+	if !h.reserveAttempt(w, r, user) {
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password)); err != nil {
+		http.Error(w, "Password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	verified, err := verifySecondFactor(context.TODO(), h.db, user, payload.Code, payload.RecoveryCode)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		http.Error(w, "Invalid two-factor code", http.StatusForbidden)
+		return
+	}
+	h.releaseAttempt(r, user)
+
+	if err := h.clearTwoFactor(user.ID); err != nil {
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes after checking
+// a current code. The old codes stop working. Wrong codes count against the
+// account's login throttle, so a stolen access token cannot be used to guess
+// a code and mint recovery codes.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	var payload models.TwoFactorCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": claims.UserID}).Decode(&user); err != nil {
+		http.Error(w, "User not found or invalid", http.StatusUnauthorized)
+		return
+	}
+	if !user.TOTPEnabled {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+	if !h.reserveAttempt(w, r, user) {
+		return
+	}
+
+	verified, err := verifySecondFactor(context.TODO(), h.db, user, payload.Code, "")
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		http.Error(w, "Invalid two-factor code", http.StatusForbidden)
+		return
+	}
+	h.releaseAttempt(r, user)
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	_, err = h.db.Collection("users").UpdateOne(context.TODO(),
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"recovery_code_hashes": hashes}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to store recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"recoveryCodes": codes})
+}
+
+// ResetUserTwoFactor turns two-factor authentication off for any account, for
+// users who lost both their device and their recovery codes. The account is
+// signed out everywhere, since whoever has the lost device may hold a session.
+func (h *TwoFactorHandler) ResetUserTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid User ID format", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	err = h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return
+	}
+	// An admin must not weaken the account of someone more privileged.
+	if !auth.HasRole(claims.Role, user.Role) {
+		http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	if err := h.clearTwoFactor(userID); err != nil {
+		http.Error(w, "Failed to reset two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.sessions.RevokeAll(context.TODO(), userID, models.SessionRevokedByAdmin); err != nil {
+		log.Printf("Failed to revoke sessions of user %s: %v", userID.Hex(), err)
+	}
+	log.Printf("Admin %s reset two-factor authentication of user %s", claims.UserID.Hex(), userID.Hex())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication reset"})
+}
+
+// reserveAttempt reserves an attempt on the account's login throttle before a
+// password or code is checked, answering 429 when the account is throttled.
+func (h *TwoFactorHandler) reserveAttempt(w http.ResponseWriter, r *http.Request, user models.User) bool {
+	wait, err := h.throttle.Reserve(r.Context(), throttle.AccountKey(user.Email), throttle.AccountLimits)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return false
+	}
+	return true
+}
+
+// releaseAttempt gives back the attempt reserved by reserveAttempt once the
+// check succeeded.
+func (h *TwoFactorHandler) releaseAttempt(r *http.Request, user models.User) {
+	if err := h.throttle.Release(r.Context(), throttle.AccountKey(user.Email)); err != nil {
+		log.Printf("Failed to release two-factor attempt for %s: %v", user.Email, err)
+	}
+}
+
+// clearTwoFactor removes the user's TOTP secret and recovery codes.
+func (h *TwoFactorHandler) clearTwoFactor(userID primitive.ObjectID) error {
+	_, err := h.db.Collection("users").UpdateOne(context.TODO(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{"totp_enabled": false},
+			"$unset": bson.M{
+				"totp_secret":          "",
+				"totp_pending_secret":  "",
+				"totp_last_step":       "",
+				"recovery_code_hashes": "",
+			},
+		},
+	)
+	return err
+}
+
+// verifySecondFactor checks a TOTP code or, failing that, a recovery code.
+// Accepted TOTP steps and recovery codes are consumed in the same update
+// that checks them, so neither can be used twice.
+func verifySecondFactor(ctx context.Context, db *mongo.Database, user models.User, code, recoveryCode string) (bool, error) {
+	users := db.Collection("users")
+
+	if code != "" {
+		step, valid := totp.Validate(user.TOTPSecret, code, time.Now())
+		if !valid {
+			return false, nil
+		}
+		result, err := users.UpdateOne(ctx,
+			bson.M{"_id": user.ID, "$or": bson.A{
+				bson.M{"totp_last_step": bson.M{"$lt": step}},
+				bson.M{"totp_last_step": bson.M{"$exists": false}},
+			}},
+			bson.M{"$set": bson.M{"totp_last_step": step}},
+		)
+		if err != nil {
+			return false, err
+		}
+		return result.MatchedCount == 1, nil
+	}
+
+	if recoveryCode != "" {
+		hash := auth.HashToken(normalizeRecoveryCode(recoveryCode))
+		result, err := users.UpdateOne(ctx,
+			bson.M{"_id": user.ID, "recovery_code_hashes": hash},
+			bson.M{"$pull": bson.M{"recovery_code_hashes": hash}},
+		)
+		if err != nil {
+			return false, err
+		}
+		return result.MatchedCount == 1, nil
+	}
+
+	return false, nil
+}
+
+// newRecoveryCodes returns fresh recovery codes, formatted like
+// "abcde-fghij", and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, auth.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes, as users retype codes.
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
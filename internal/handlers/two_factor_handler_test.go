@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestVerifySecondFactorRejectsReplayedCode(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{
+		ID:          primitive.NewObjectID(),
+		Email:       "dosen@unsrat.ac.id",
+		TOTPEnabled: true,
+		TOTPSecret:  secret,
+	}
+	if _, err := db.Collection("users").InsertOne(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := verifySecondFactor(ctx, db, user, code, ""); err != nil || !ok {
+		t.Fatalf("first use: got %v, %v, want true", ok, err)
+	}
+	if ok, err := verifySecondFactor(ctx, db, user, code, ""); err != nil || ok {
+		t.Errorf("replay: got %v, %v, want false", ok, err)
+	}
+
+	// A code from an earlier step that is still inside the skew window must
+	// not be accepted after a later one.
+	earlier, err := totp.Code(secret, step-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := verifySecondFactor(ctx, db, user, earlier, ""); err != nil || ok {
+		t.Errorf("earlier step: got %v, %v, want false", ok, err)
+	}
+}
+
+func TestRegenerateRecoveryCodesIsThrottled(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{
+		ID:          primitive.NewObjectID(),
+		Email:       "mahasiswa@student.unsrat.ac.id",
+		Role:        auth.RoleStudent,
+		TOTPEnabled: true,
+		TOTPSecret:  secret,
+	}
+	if _, err := db.Collection("users").InsertOne(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewTwoFactorHandler(db, sessions.NewStore(db, time.Hour), nil, config.Config{TOTPIssuer: "JTE Ticketing"})
+	claims := &auth.Claims{UserID: user.ID, Email: user.Email, Role: user.Role}
+	guess := func() int {
+		// Letters never match a TOTP code.
+		req := httptest.NewRequest(http.MethodPost, "/api/2fa/recovery-codes", strings.NewReader(`{"code":"abcdef"}`))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ClaimsKey, claims))
+		rec := httptest.NewRecorder()
+		h.RegenerateRecoveryCodes(rec, req)
+		return rec.Code
+	}
+
+	for i := 1; i <= 5; i++ {
+		code := guess()
+		if code != http.StatusForbidden && code != http.StatusTooManyRequests {
+			t.Fatalf("guess %d: status %d", i, code)
+		}
+	}
+	if code := guess(); code != http.StatusTooManyRequests {
+		t.Errorf("sixth guess: got status %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestResetUserTwoFactorRevokesSessions(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	user := models.User{ID: primitive.NewObjectID(), Email: "dosen@unsrat.ac.id", Role: auth.RoleStudent, TOTPEnabled: true, TOTPSecret: "GEZDGNBVGY3TQOJQ"}
+	if _, err := db.Collection("users").InsertOne(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	store := sessions.NewStore(db, time.Hour)
+	session, _, err := store.Create(ctx, user.ID, "lost phone", "10.0.0.1", []string{auth.AMRPassword, auth.AMROTP})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewTwoFactorHandler(db, store, nil, config.Config{})
+	claims := &auth.Claims{UserID: primitive.NewObjectID(), Role: auth.RoleAdmin}
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+user.ID.Hex()+"/2fa/reset", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ClaimsKey, claims))
+	req = mux.SetURLVars(req, map[string]string{"id": user.ID.Hex()})
+	rec := httptest.NewRecorder()
+	h.ResetUserTwoFactor(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rec.Code)
+	}
+	if err := store.ValidateSession(ctx, session.ID); err == nil {
+		t.Error("session is still valid after the reset")
+	}
+}
@@ -4,13 +4,25 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/middleware" // Import the middleware package
 	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/throttle"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/usertokens"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -20,14 +32,46 @@ import (
 
 // UserHandler handles user-related HTTP requests.
 type UserHandler struct {
-	db *mongo.Database
+	db       *mongo.Database
+	sessions *sessions.Store
+	tokens   *auth.Tokens
+	accounts *AccountHandler
+	throttle *throttle.Store
+	cookies  authCookies
+	proxies  trustedProxies
+
+	// challengeTTL is how long a two-step login challenge stays valid.
+	challengeTTL time.Duration
+
+	// dummyHash is compared against when a login names an unknown email,
+	// so that takes as long as a wrong password.
+	dummyHash []byte
 }
 
-// NewUserHandler creates a new UserHandler.
-func NewUserHandler(db *mongo.Database) *UserHandler {
-	return &UserHandler{db: db}
+// NewUserHandler creates a new UserHandler. Registration uses accounts to
+// validate addresses and send verification emails.
+func NewUserHandler(db *mongo.Database, sessionStore *sessions.Store, tokens *auth.Tokens, accounts *AccountHandler, cfg config.Config) *UserHandler {
+	dummyHash, err := accounts.passwords.Hash("dummy password")
+	if err != nil {
+		log.Printf("Failed to prepare dummy password hash: %v", err)
+	}
+	return &UserHandler{
+		db:           db,
+		sessions:     sessionStore,
+		tokens:       tokens,
+		accounts:     accounts,
+		throttle:     throttle.NewStore(db),
+		cookies:      newAuthCookies(cfg),
+		proxies:      trustedProxies(cfg.TrustedProxies),
+		challengeTTL: cfg.LoginChallengeTTL,
+		dummyHash:    dummyHash,
+	}
 }
 
+// refreshCookieName is the HttpOnly cookie holding the refresh token. It is
+// scoped to /api so it reaches the refresh and logout endpoints.
+const refreshCookieName = "refresh_token"
+
 func (h *UserHandler) ValidateToken(w http.ResponseWriter, r *http.Request) {
 	// Get user claims from context using the exported key from the middleware package.
 	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
@@ -65,24 +109,41 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	email := normalizeEmail(creds.Email)
+	ip := h.proxies.clientIP(r)
+	attempt := models.LoginLog{Email: email, IP: ip, UserAgent: r.UserAgent()}
+
+	// The attempt counts as a failure until the password turns out right.
+	wait, err := h.reserveLoginAttempt(r.Context(), email, ip)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		attempt.Reason = models.LoginFailureThrottled
+		go h.logLogin(attempt)
+		writeTooManyAttempts(w, wait)
+		return
+	}
+
 	var user models.User
 	collection := h.db.Collection("users")
-	err := collection.FindOne(context.TODO(), bson.M{"email": creds.Email}).Decode(&user)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"status":  http.StatusUnauthorized,
-				"message": "Email atau password salah!",
-			})
-			return
-		}
+	err = collection.FindOne(context.TODO(), bson.M{"email": email}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password)); err != nil {
+	if err == mongo.ErrNoDocuments {
+		bcrypt.CompareHashAndPassword(h.dummyHash, []byte(creds.Password))
+		attempt.Reason = models.LoginFailureUnknownEmail
+	} else if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password)); err != nil {
+		attempt.UserID = user.ID
+		attempt.Reason = models.LoginFailureInvalidPassword
+	}
+	if attempt.Reason != "" {
+		go h.logLogin(attempt)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -91,29 +152,161 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	attempt.UserID = user.ID
+	h.releaseLoginAttempt(r.Context(), email, ip)
+
+	if !user.EmailVerified {
+		attempt.Reason = models.LoginFailureEmailNotVerified
+		go h.logLogin(attempt)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  http.StatusForbidden,
+			"message": "Email belum diverifikasi. Silakan cek email Anda.",
+		})
+		return
+	}
+
+	if user.Disabled {
+		attempt.Reason = models.LoginFailureAccountDisabled
+		go h.logLogin(attempt)
+		writeAccountDisabled(w)
+		return
+	}
+
+	// Upgrade hashes made with an older, cheaper cost while the plain
+	// password is at hand.
+	if h.accounts.passwords.NeedsRehash([]byte(user.Password)) {
+		h.rehashPassword(user.ID, creds.Password)
+	}
+
+	// With two-factor authentication the password only earns a challenge
+	// token, which LoginTwoFactor exchanges for a session. Codes are
+	// throttled like passwords, so they cannot be guessed without limit.
+	if user.TOTPEnabled {
+		challenge, err := h.accounts.tokens.Issue(r.Context(), user.ID, models.TokenPurposeLoginChallenge, h.challengeTTL)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":            http.StatusOK,
+			"message":           "Masukkan kode autentikasi dua faktor.",
+			"twoFactorRequired": true,
+			"challengeToken":    challenge,
+			"expiresIn":         int(h.challengeTTL.Seconds()),
+		})
+		return
+	}
+
+	h.completeLogin(w, r, user, attempt, []string{auth.AMRPassword})
+}
+
+// LoginTwoFactor finishes a two-step login with the challenge token from
+// Login and either a TOTP code or a recovery code. A challenge token can only
+// be used once; a wrong code means logging in again.
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload models.TwoFactorLoginPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if payload.ChallengeToken == "" || (payload.Code == "" && payload.RecoveryCode == "") {
+		http.Error(w, "challengeToken and code or recoveryCode are required", http.StatusBadRequest)
+		return
+	}
 
-	tokenString, err := auth.GenerateJWT(user.ID, user.Email, user.Role)
+	challenge, err := h.accounts.tokens.Consume(r.Context(), payload.ChallengeToken, models.TokenPurposeLoginChallenge)
+	if errors.Is(err, usertokens.ErrInvalidToken) {
+		http.Error(w, "Invalid or expired challenge, please log in again", http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	go h.logLoginSession(user.ID, r.UserAgent())
+	var user models.User
+	if err := h.db.Collection("users").FindOne(r.Context(), bson.M{"_id": challenge.UserID}).Decode(&user); err != nil {
+		http.Error(w, "User not found or invalid", http.StatusUnauthorized)
+		return
+	}
+
+	ip := h.proxies.clientIP(r)
+	attempt := models.LoginLog{UserID: user.ID, Email: user.Email, IP: ip, UserAgent: r.UserAgent()}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    tokenString,
-		Expires:  time.Now().Add(120 * time.Minute),
-		HttpOnly: true,
-	})
+	if user.Disabled {
+		attempt.Reason = models.LoginFailureAccountDisabled
+		go h.logLogin(attempt)
+		writeAccountDisabled(w)
+		return
+	}
+
+	wait, err := h.reserveLoginAttempt(r.Context(), user.Email, ip)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		attempt.Reason = models.LoginFailureThrottled
+		go h.logLogin(attempt)
+		writeTooManyAttempts(w, wait)
+		return
+	}
+
+	verified, err := verifySecondFactor(r.Context(), h.db, user, payload.Code, payload.RecoveryCode)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		attempt.Reason = models.LoginFailureInvalidTwoFactor
+		go h.logLogin(attempt)
+		http.Error(w, "Invalid two-factor code, please log in again", http.StatusUnauthorized)
+		return
+	}
+	h.releaseLoginAttempt(r.Context(), user.Email, ip)
+
+	h.completeLogin(w, r, user, attempt, []string{auth.AMRPassword, auth.AMROTP})
+}
+
+// completeLogin starts a session for an authenticated user and answers with
+// the access and refresh tokens.
+func (h *UserHandler) completeLogin(w http.ResponseWriter, r *http.Request, user models.User, attempt models.LoginLog, amr []string) {
+	if err := h.throttle.Reset(r.Context(), throttle.AccountKey(user.Email)); err != nil {
+		log.Printf("Failed to reset login throttle for %s: %v", user.Email, err)
+	}
+
+	session, refreshToken, err := h.sessions.Create(r.Context(), user.ID, r.UserAgent(), h.proxies.clientIP(r), amr)
+	if err != nil {
+		log.Printf("Failed to create session for user %s: %v", user.ID.Hex(), err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	attempt.SessionID = session.ID
+
+	tokenString, err := h.tokens.GenerateJWT(user.ID, user.Email, user.Role, session.ID, session.AMR)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	attempt.Success = true
+	go h.logLogin(attempt)
+
+	h.cookies.set(w, tokenString, refreshToken, session.ExpiresAt)
 
 	// Return token, user data, status, and message in the response body
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  http.StatusOK,
-		"message": "Berhasil Masuk!",
-		"token":   tokenString,
+		"status":       http.StatusOK,
+		"message":      "Berhasil Masuk!",
+		"token":        tokenString,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(h.cookies.accessTTL.Seconds()),
 		"user": map[string]string{
 			"email": user.Email,
 			"role":  user.Role,
@@ -121,7 +314,62 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Register creates a new user.
+// RefreshToken rotates the refresh token and issues a new access token.
+// Replaying a rotated refresh token revokes the whole session.
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	token, err := refreshTokenFromRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if token == "" {
+		http.Error(w, "Missing refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	session, refreshToken, err := h.sessions.Rotate(r.Context(), token, r.UserAgent(), h.proxies.clientIP(r))
+	if err != nil {
+		h.cookies.clear(w)
+		if errors.Is(err, sessions.ErrTokenReused) {
+			log.Printf("Refresh token reuse detected from %s; session revoked", h.proxies.clientIP(r))
+			http.Error(w, "Refresh token reuse detected, please log in again", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, sessions.ErrInvalidToken) {
+			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Reload the user so role changes apply to the new access token.
+	var user models.User
+	if err := h.db.Collection("users").FindOne(r.Context(), bson.M{"_id": session.UserID}).Decode(&user); err != nil {
+		h.cookies.clear(w)
+		http.Error(w, "User not found or invalid", http.StatusUnauthorized)
+		return
+	}
+
+	tokenString, err := h.tokens.GenerateJWT(user.ID, user.Email, user.Role, session.ID, session.AMR)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.cookies.set(w, tokenString, refreshToken, session.ExpiresAt)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       http.StatusOK,
+		"token":        tokenString,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(h.cookies.accessTTL.Seconds()),
+	})
+}
+
+// Register creates a new, unverified user and mails a verification link.
+// Only addresses from the allowed institutional domains are accepted.
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var creds models.Credentials
 	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
@@ -129,7 +377,17 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(creds.Password), 8)
+	email, err := h.accounts.validateEmail(creds.Email)
+	if err != nil {
+		http.Error(w, "Invalid email: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if problems := h.accounts.passwords.Validate(creds.Password, email); len(problems) > 0 {
+		writePasswordProblems(w, problems)
+		return
+	}
+
+	hashedPassword, err := h.accounts.passwords.Hash(creds.Password)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -137,9 +395,9 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	newUser := models.User{
 		ID:       primitive.NewObjectID(),
-		Email:    creds.Email,
+		Email:    email,
 		Password: string(hashedPassword),
-		Role:     "student",
+		Role:     auth.RoleStudent,
 	}
 
 	collection := h.db.Collection("users")
@@ -153,39 +411,156 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.accounts.sendVerification(context.TODO(), newUser); err != nil {
+		// The account exists; the user can ask for a new link.
+		log.Printf("Failed to issue verification token for user %s: %v", newUser.ID.Hex(), err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "User created successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": "User created successfully. Please verify your email before logging in."})
 }
 
-// Logout handles user logout by clearing the authentication cookie.
+// Logout revokes the current session and clears the authentication cookies.
+// The session is found from the refresh token, or failing that from the
+// access token, so logging out works even after the access token expired.
 func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Unix(0, 0),
-		HttpOnly: true,
-		MaxAge:   -1,
-	})
+	var sessionID primitive.ObjectID
+	if token, err := refreshTokenFromRequest(r); err == nil && token != "" {
+		if session, err := h.sessions.FindByToken(r.Context(), token); err == nil {
+			sessionID = session.ID
+		}
+	}
+	if sessionID.IsZero() {
+		if cookie, err := r.Cookie("token"); err == nil {
+			if claims, err := h.tokens.ValidateJWT(cookie.Value); err == nil {
+				sessionID = claims.SessionID
+			}
+		}
+	}
+	if !sessionID.IsZero() {
+		if err := h.sessions.Revoke(r.Context(), sessionID, models.SessionRevokedLogout); err != nil {
+			log.Printf("Failed to revoke session %s: %v", sessionID.Hex(), err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.cookies.clear(w)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully logged out"})
 }
 
-func (h *UserHandler) logLoginSession(userID primitive.ObjectID, userAgent string) {
+// logLogin records a login attempt, successful or not.
+func (h *UserHandler) logLogin(entry models.LoginLog) {
 	logCollection := h.db.Collection("login_logs")
 
-	logEntry := models.LoginLog{
-		ID:        primitive.NewObjectID(),
-		UserID:    userID,
-		Timestamp: time.Now(),
-		UserAgent: userAgent,
+	entry.ID = primitive.NewObjectID()
+	entry.Timestamp = time.Now()
+
+	_, err := logCollection.InsertOne(context.TODO(), entry)
+	if err != nil {
+		log.Printf("Failed to create login log for %s: %v", entry.Email, err)
 	}
+}
 
-	_, err := logCollection.InsertOne(context.TODO(), logEntry)
+// rehashPassword stores a new hash of the password with the current cost.
+func (h *UserHandler) rehashPassword(userID primitive.ObjectID, plain string) {
+	hashedPassword, err := h.accounts.passwords.Hash(plain)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %s: %v", userID.Hex(), err)
+		return
+	}
+	_, err = h.db.Collection("users").UpdateOne(context.TODO(),
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"password": string(hashedPassword)}},
+	)
 	if err != nil {
-		log.Printf("Failed to create login log for user %s: %v", userID.Hex(), err)
+		log.Printf("Failed to store rehashed password for user %s: %v", userID.Hex(), err)
+	}
+}
+
+// reserveLoginAttempt counts a login attempt against the account and the
+// client IP before the credentials are checked. It returns how long the
+// client must wait when either of them is blocked.
+func (h *UserHandler) reserveLoginAttempt(ctx context.Context, email, ip string) (time.Duration, error) {
+	wait, err := h.throttle.Reserve(ctx, throttle.AccountKey(email), throttle.AccountLimits)
+	if err != nil || wait > 0 {
+		return wait, err
 	}
+	wait, err = h.throttle.Reserve(ctx, throttle.IPKey(ip), throttle.IPLimits)
+	if err != nil || wait > 0 {
+		if releaseErr := h.throttle.Release(ctx, throttle.AccountKey(email)); releaseErr != nil {
+			log.Printf("Failed to release login attempt for %s: %v", email, releaseErr)
+		}
+		return wait, err
+	}
+	return 0, nil
+}
+
+// releaseLoginAttempt takes back an attempt whose credentials were right.
+func (h *UserHandler) releaseLoginAttempt(ctx context.Context, email, ip string) {
+	if err := h.throttle.Release(ctx, throttle.AccountKey(email)); err != nil {
+		log.Printf("Failed to release login attempt for %s: %v", email, err)
+	}
+	if err := h.throttle.Release(ctx, throttle.IPKey(ip)); err != nil {
+		log.Printf("Failed to release login attempt for %s: %v", ip, err)
+	}
+}
+
+// writeTooManyAttempts answers a throttled login with 429 and Retry-After.
+func writeTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     http.StatusTooManyRequests,
+		"message":    fmt.Sprintf("Terlalu banyak percobaan masuk. Coba lagi dalam %d detik.", seconds),
+		"retryAfter": seconds,
+	})
+}
+
+// UnlockUser clears the failed login counter of an account, lifting a lockout.
+// Pass ip to also unlock a client address.
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized: Could not retrieve user claims.", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid User ID format", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	err = h.db.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.throttle.Reset(r.Context(), throttle.AccountKey(user.Email)); err != nil {
+		http.Error(w, "Failed to unlock user", http.StatusInternalServerError)
+		return
+	}
+	if ip := r.URL.Query().Get("ip"); ip != "" {
+		if err := h.throttle.Reset(r.Context(), throttle.IPKey(ip)); err != nil {
+			http.Error(w, "Failed to unlock IP address", http.StatusInternalServerError)
+			return
+		}
+	}
+	log.Printf("Admin %s unlocked login for user %s", claims.UserID.Hex(), user.ID.Hex())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User unlocked"})
 }
 
 func (h *UserHandler) GetLoginLogs(w http.ResponseWriter, r *http.Request) {
@@ -220,3 +595,127 @@ func (h *UserHandler) GetLoginLogs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(logs)
 }
+
+// writeAccountDisabled answers a login to a disabled account.
+func writeAccountDisabled(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  http.StatusForbidden,
+		"message": "Akun Anda telah dinonaktifkan. Silakan hubungi admin.",
+	})
+}
+
+// authCookies sets and clears the authentication cookies with the
+// configured attributes.
+type authCookies struct {
+	secure    bool
+	sameSite  http.SameSite
+	domain    string
+	accessTTL time.Duration
+}
+
+func newAuthCookies(cfg config.Config) authCookies {
+	return authCookies{
+		secure:    cfg.CookieSecure,
+		sameSite:  cfg.CookieSameSite,
+		domain:    cfg.CookieDomain,
+		accessTTL: cfg.AccessTokenTTL,
+	}
+}
+
+// cookie builds an HttpOnly cookie with the configured attributes.
+func (c authCookies) cookie(name, value, path string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.domain,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   c.secure,
+		SameSite: c.sameSite,
+	}
+}
+
+// set stores the access and refresh tokens in their cookies.
+func (c authCookies) set(w http.ResponseWriter, accessToken, refreshToken string, refreshExpires time.Time) {
+	c.setAccess(w, accessToken)
+	http.SetCookie(w, c.cookie(refreshCookieName, refreshToken, "/api", refreshExpires))
+}
+
+// setAccess stores the access token in its cookie.
+func (c authCookies) setAccess(w http.ResponseWriter, accessToken string) {
+	http.SetCookie(w, c.cookie("token", accessToken, "/", time.Now().Add(c.accessTTL)))
+}
+
+// clear expires both authentication cookies.
+func (c authCookies) clear(w http.ResponseWriter) {
+	for _, target := range []struct{ name, path string }{{"token", "/"}, {refreshCookieName, "/api"}} {
+		cookie := c.cookie(target.name, "", target.path, time.Unix(0, 0))
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+// refreshTokenFromRequest reads the refresh token from its cookie, falling
+// back to a JSON body for clients without cookies. An empty body is not an error.
+func refreshTokenFromRequest(r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(refreshCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	var payload models.RefreshTokenPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && err != io.EOF {
+		return "", err
+	}
+	return payload.RefreshToken, nil
+}
+
+// trustedProxies are the reverse proxies whose X-Forwarded-For is believed.
+type trustedProxies []*net.IPNet
+
+// clientIP returns the address of the client. Behind trusted proxies it walks
+// X-Forwarded-For from the right, past the hops added by those proxies, and
+// returns the first address they did not vouch for; anything further left was
+// sent by the client and could be made up. Without trusted proxies the header
+// is ignored.
+func (p trustedProxies) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !p.contains(ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if net.ParseIP(hop) == nil {
+			// A garbled entry ends the chain; the last proxy is all we know.
+			break
+		}
+		ip = hop
+		if !p.contains(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+// contains reports whether ip belongs to one of the proxies.
+func (p trustedProxies) contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
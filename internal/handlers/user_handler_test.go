@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	_, internal, _ := net.ParseCIDR("10.0.0.0/8")
+	proxies := trustedProxies{internal}
+
+	tests := []struct {
+		name      string
+		proxies   trustedProxies
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"no proxy", nil, "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"header ignored without trusted proxies", nil, "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"header ignored from untrusted peer", proxies, "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"behind a proxy", proxies, "10.0.0.2:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed entries on the left", proxies, "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of proxies", proxies, "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1, 10.0.0.9"}, "198.51.100.1"},
+		{"several headers", proxies, "10.0.0.2:5000", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		{"garbled entry", proxies, "10.0.0.2:5000", []string{"198.51.100.1, nonsense"}, "10.0.0.2"},
+		{"only proxies", proxies, "10.0.0.2:5000", []string{"10.0.0.9"}, "10.0.0.9"},
+		{"no header", proxies, "10.0.0.2:5000", nil, "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		for _, value := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		if got := tt.proxies.clientIP(r); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
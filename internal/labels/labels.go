@@ -0,0 +1,168 @@
+// Package labels renders printable asset labels: a QR code or Code 128
+// barcode with human-readable captions underneath.
+package labels
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Barcode formats.
+const (
+	FormatQR      = "qr"
+	FormatCode128 = "code128"
+)
+
+// Size of a single label in pixels. At 203 dpi, the usual label printer
+// resolution, a label is about 30 × 26 mm.
+const (
+	Width  = 240
+	Height = 210
+)
+
+const (
+	margin        = 10
+	codeHeight    = 150 // room for the barcode above the captions
+	qrSize        = 120
+	code128Height = 90
+	// code128QuietZone is the blank space, in bar widths, scanners need on
+	// each side of a Code 128 barcode.
+	code128QuietZone = 10
+	lineHeight       = 16
+	sheetGap         = 20
+)
+
+// Label is the content of one label. Value is what the barcode encodes;
+// Caption lines are printed below it and are cut to fit.
+type Label struct {
+	Value   string
+	Caption []string
+}
+
+// Render draws a single label.
+func Render(label Label, format string) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	if err := drawLabel(img, image.Point{}, label, format); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Sheet lays the labels out in a grid with the given number of columns,
+// ready to print and cut.
+func Sheet(labels []Label, format string, columns int) (*image.RGBA, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("labels: no labels to render")
+	}
+	if columns < 1 {
+		return nil, fmt.Errorf("labels: columns must be at least 1")
+	}
+	columns = min(columns, len(labels))
+	rows := (len(labels) + columns - 1) / columns
+
+	img := image.NewRGBA(image.Rect(0, 0,
+		columns*Width+(columns+1)*sheetGap,
+		rows*Height+(rows+1)*sheetGap))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for i, label := range labels {
+		origin := image.Pt(
+			sheetGap+(i%columns)*(Width+sheetGap),
+			sheetGap+(i/columns)*(Height+sheetGap),
+		)
+		if err := drawLabel(img, origin, label, format); err != nil {
+			return nil, err
+		}
+		// A light border shows where to cut.
+		drawBorder(img, image.Rectangle{Min: origin, Max: origin.Add(image.Pt(Width, Height))})
+	}
+	return img, nil
+}
+
+// drawLabel draws the label with its top-left corner at origin.
+func drawLabel(img *image.RGBA, origin image.Point, label Label, format string) error {
+	code, err := encode(label.Value, format)
+	if err != nil {
+		return err
+	}
+	bounds := code.Bounds()
+	at := origin.Add(image.Pt((Width-bounds.Dx())/2, margin+(codeHeight-bounds.Dy())/2))
+	draw.Draw(img, image.Rectangle{Min: at, Max: at.Add(bounds.Size())}, code, bounds.Min, draw.Src)
+
+	drawer := font.Drawer{Dst: img, Src: image.Black, Face: basicfont.Face7x13}
+	maxWidth := fixed.I(Width - 2*margin)
+	for i, line := range label.Caption {
+		line = fit(&drawer, line, maxWidth)
+		width := drawer.MeasureString(line)
+		drawer.Dot = fixed.Point26_6{
+			X: fixed.I(origin.X) + (fixed.I(Width)-width)/2,
+			Y: fixed.I(origin.Y + margin + codeHeight + (i+1)*lineHeight),
+		}
+		drawer.DrawString(line)
+	}
+	return nil
+}
+
+// encode renders value as a barcode scaled to fit the code area with a quiet
+// zone around it. Code 128 bars are only scaled by whole multiples so every
+// bar keeps the same width.
+func encode(value, format string) (barcode.Barcode, error) {
+	switch format {
+	case FormatQR:
+		code, err := qr.Encode(value, qr.M, qr.Auto)
+		if err != nil {
+			return nil, fmt.Errorf("labels: encoding QR code: %w", err)
+		}
+		return barcode.Scale(code, qrSize, qrSize)
+	case FormatCode128:
+		code, err := code128.Encode(value)
+		if err != nil {
+			return nil, fmt.Errorf("labels: encoding Code 128: %w", err)
+		}
+		modules := code.Bounds().Dx()
+		scale := Width / (modules + 2*code128QuietZone)
+		if scale < 1 {
+			return nil, fmt.Errorf("labels: %q is too long for a Code 128 label", value)
+		}
+		return barcode.Scale(code, modules*scale, code128Height)
+	default:
+		return nil, fmt.Errorf("labels: unknown format %q", format)
+	}
+}
+
+// fit shortens line until it is at most maxWidth wide. Characters the font
+// cannot draw are replaced by '?'.
+func fit(drawer *font.Drawer, line string, maxWidth fixed.Int26_6) string {
+	runes := []rune(line)
+	for i, r := range runes {
+		if r < ' ' || r > '~' {
+			runes[i] = '?'
+		}
+	}
+	for len(runes) > 0 && drawer.MeasureString(string(runes)) > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes)
+}
+
+func drawBorder(img *image.RGBA, r image.Rectangle) {
+	gray := color.Gray{Y: 200}
+	for x := r.Min.X; x < r.Max.X; x++ {
+		img.Set(x, r.Min.Y, gray)
+		img.Set(x, r.Max.Y-1, gray)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		img.Set(r.Min.X, y, gray)
+		img.Set(r.Max.X-1, y, gray)
+	}
+}
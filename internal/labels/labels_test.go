@@ -0,0 +1,194 @@
+package labels
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+func isDark(img *image.RGBA, x, y int) bool {
+	r, _, _, _ := img.At(x, y).RGBA()
+	return r < 0x8000
+}
+
+// darkBounds returns the smallest rectangle within area holding every dark pixel.
+func darkBounds(img *image.RGBA, area image.Rectangle) image.Rectangle {
+	var found image.Rectangle
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if isDark(img, x, y) {
+				found = found.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return found
+}
+
+func TestRender(t *testing.T) {
+	label := Label{Value: "LPT-0001", Caption: []string{"Laptop Lenovo", "SN 12345"}}
+	codeArea := image.Rect(0, 0, Width, margin+codeHeight)
+	captionArea := image.Rect(0, margin+codeHeight, Width, Height)
+
+	for _, format := range []string{FormatQR, FormatCode128} {
+		t.Run(format, func(t *testing.T) {
+			img, err := Render(label, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := img.Bounds(); got != image.Rect(0, 0, Width, Height) {
+				t.Fatalf("bounds: got %v, want %dx%d", got, Width, Height)
+			}
+
+			code := darkBounds(img, codeArea)
+			if code.Empty() {
+				t.Fatal("no barcode drawn")
+			}
+			// The code is centred horizontally.
+			if left, right := code.Min.X, Width-code.Max.X; left-right > 1 || right-left > 1 {
+				t.Errorf("barcode not centred: %d px left, %d px right", left, right)
+			}
+			if darkBounds(img, captionArea).Empty() {
+				t.Error("no caption drawn")
+			}
+		})
+	}
+}
+
+func TestRenderQRSize(t *testing.T) {
+	img, err := Render(Label{Value: "LPT-0001"}, FormatQR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := darkBounds(img, img.Bounds())
+	// Finder patterns sit in three corners, so the dark area spans the code.
+	// Modules are scaled by whole pixels, which may leave some of the
+	// reserved square blank.
+	if code.Dx() != code.Dy() || code.Dx() > qrSize || code.Dx() < qrSize*3/4 {
+		t.Errorf("QR code: got %dx%d, want a square of at most %d px", code.Dx(), code.Dy(), qrSize)
+	}
+}
+
+func TestCode128KeepsWholeBarWidthsAndQuietZone(t *testing.T) {
+	code, err := encode("LPT-0001", FormatCode128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := code.Bounds()
+	if bounds.Dy() != code128Height {
+		t.Errorf("height: got %d, want %d", bounds.Dy(), code128Height)
+	}
+
+	// Measure the runs of equal colour across one row.
+	var runs []int
+	y := bounds.Min.Y
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		dark := code.At(x, y) == color.Black
+		if x == bounds.Min.X || dark != (code.At(x-1, y) == color.Black) {
+			runs = append(runs, 0)
+		}
+		runs[len(runs)-1]++
+	}
+	narrowest := bounds.Dx()
+	for _, run := range runs {
+		narrowest = min(narrowest, run)
+	}
+	for i, run := range runs {
+		if run%narrowest != 0 {
+			t.Fatalf("run %d is %d px wide, not a multiple of the %d px module", i, run, narrowest)
+		}
+	}
+	if quiet := (Width - bounds.Dx()) / 2; quiet < code128QuietZone*narrowest {
+		t.Errorf("quiet zone: got %d px, want at least %d", quiet, code128QuietZone*narrowest)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		format string
+	}{
+		{"unknown format", "LPT-0001", "ean13"},
+		{"too long for Code 128", strings.Repeat("LPT-0001", 10), FormatCode128},
+		{"not encodable in Code 128", "LPT-ÿ", FormatCode128},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Render(Label{Value: tt.value}, tt.format); err == nil {
+				t.Error("label was rendered")
+			}
+		})
+	}
+}
+
+func TestSheet(t *testing.T) {
+	labels := make([]Label, 5)
+	for i := range labels {
+		labels[i] = Label{Value: "LPT-000" + string(rune('1'+i))}
+	}
+
+	img, err := Sheet(labels, FormatQR, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantW, wantH := 2*Width+3*sheetGap, 3*Height+4*sheetGap
+	if got := img.Bounds(); got.Dx() != wantW || got.Dy() != wantH {
+		t.Fatalf("sheet: got %dx%d, want %dx%d", got.Dx(), got.Dy(), wantW, wantH)
+	}
+
+	cell := func(column, row int) image.Rectangle {
+		origin := image.Pt(sheetGap+column*(Width+sheetGap), sheetGap+row*(Height+sheetGap))
+		return image.Rectangle{Min: origin, Max: origin.Add(image.Pt(Width, Height))}
+	}
+	for i := range labels {
+		r := cell(i%2, i/2)
+		if img.At(r.Min.X, r.Min.Y) != (color.RGBA{200, 200, 200, 255}) {
+			t.Errorf("label %d: no cutting border at %v", i, r.Min)
+		}
+		if darkBounds(img, r.Inset(1)).Empty() {
+			t.Errorf("label %d: nothing drawn in %v", i, r)
+		}
+	}
+	// The last row has a single label; the rest of it stays blank.
+	if !darkBounds(img, cell(1, 2)).Empty() {
+		t.Error("empty cell is not blank")
+	}
+}
+
+func TestSheetLimitsColumnsToLabels(t *testing.T) {
+	img, err := Sheet([]Label{{Value: "LPT-0001"}}, FormatCode128, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds().Dx(); got != Width+2*sheetGap {
+		t.Errorf("width: got %d, want %d", got, Width+2*sheetGap)
+	}
+}
+
+func TestSheetErrors(t *testing.T) {
+	if _, err := Sheet(nil, FormatQR, 2); err == nil {
+		t.Error("empty sheet was rendered")
+	}
+	if _, err := Sheet([]Label{{Value: "LPT-0001"}}, FormatQR, 0); err == nil {
+		t.Error("sheet with no columns was rendered")
+	}
+}
+
+func TestFit(t *testing.T) {
+	drawer := &font.Drawer{Face: basicfont.Face7x13}
+	maxWidth := fixed.I(Width - 2*margin)
+
+	long := strings.Repeat("Laptop ", 10)
+	got := fit(drawer, long, maxWidth)
+	if drawer.MeasureString(got) > maxWidth || !strings.HasPrefix(long, got) {
+		t.Errorf("fit(%q) = %q, want a prefix no wider than %v", long, got, maxWidth)
+	}
+	if got := fit(drawer, "Proyektor Epsön", maxWidth); got != "Proyektor Eps?n" {
+		t.Errorf("got %q, want %q", got, "Proyektor Eps?n")
+	}
+}
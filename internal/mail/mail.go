@@ -0,0 +1,38 @@
+// Package mail sends transactional emails such as password reset links.
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the Mailer selected by cfg.MailTransport.
+func New(cfg config.Config) (Mailer, error) {
+	switch cfg.MailTransport {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("mail: SMTP_HOST is required for the smtp transport")
+		}
+		return NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "file":
+		return NewFile(cfg.MailDir, cfg.MailFrom), nil
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("mail: unknown transport %q", cfg.MailTransport)
+	}
+}
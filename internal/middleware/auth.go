@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // UserClaimsKey is the type for our context key. Using a custom type
@@ -17,46 +19,116 @@ type UserClaimsKey string
 // to access the user claims in the context.
 const ClaimsKey UserClaimsKey = "userClaims"
 
-// Auth is a middleware that checks for a valid JWT from either a cookie or Authorization header.
-func Auth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := ""
+// SessionValidator reports whether the session behind an access token is
+// still live. It returns an error for revoked or expired sessions.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, id primitive.ObjectID) error
+}
 
-		// 1. Try to get the token from the HttpOnly cookie first.
-		cookie, err := r.Cookie("token")
-		if err == nil {
-			tokenString = cookie.Value
-		}
+// AccountValidator reports whether the user behind an access token may still
+// use the API. It returns an error for disabled or deleted accounts.
+type AccountValidator interface {
+	ValidateAccount(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// Authenticator checks access tokens, and rejects those whose session has
+// been revoked or whose account has been disabled.
+type Authenticator struct {
+	tokens   *auth.Tokens
+	sessions SessionValidator
+	accounts AccountValidator
+}
 
-		// 2. If no cookie, try to get it from the Authorization header.
-		if tokenString == "" {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
+// NewAuthenticator creates an Authenticator.
+func NewAuthenticator(tokens *auth.Tokens, sessions SessionValidator, accounts AccountValidator) *Authenticator {
+	return &Authenticator{tokens: tokens, sessions: sessions, accounts: accounts}
+}
+
+var (
+	errNoToken         = errors.New("missing authorization token")
+	errMalformedBearer = errors.New("invalid authorization header format")
+)
+
+// Auth is a middleware that checks for a valid JWT from either a cookie or Authorization header.
+func (a *Authenticator) Auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 1. Find the token in the cookie or the Authorization header.
+		tokenString, err := tokenFromRequest(r)
+		if err != nil {
+			if err == errNoToken {
 				log.Println("Auth Error: No token found in cookie or Authorization header")
 				http.Error(w, "Missing authorization token", http.StatusUnauthorized)
 				return
 			}
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				log.Println("Auth Error: Invalid Authorization header format")
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
-				return
-			}
-			tokenString = parts[1]
+			log.Println("Auth Error: Invalid Authorization header format")
+			http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+			return
 		}
 
-		// 3. Validate the token we found.
-		claims, err := auth.ValidateJWT(tokenString)
+		// 2. Validate the token we found.
+		claims, err := a.tokens.ValidateJWT(tokenString)
 		if err != nil {
 			log.Printf("Auth Error: Token validation failed. Error: %v", err)
 			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
+		if err := a.validateSession(r.Context(), claims); err != nil {
+			log.Printf("Auth Error: Session %s rejected. Error: %v", claims.SessionID.Hex(), err)
+			http.Error(w, "Session has been revoked, please log in again", http.StatusUnauthorized)
+			return
+		}
+		if err := a.accounts.ValidateAccount(r.Context(), claims.UserID); err != nil {
+			log.Printf("Auth Error: Account %s rejected. Error: %v", claims.UserID.Hex(), err)
+			http.Error(w, "Forbidden: account has been disabled", http.StatusForbidden)
+			return
+		}
 
-		// 4. If the token is valid, add claims to the request context using our exported key.
+		// 3. If the token is valid, add claims to the request context using our exported key.
 		ctx := context.WithValue(r.Context(), ClaimsKey, claims)
 
-		// 5. Call the next handler in the chain.
+		// 4. Call the next handler in the chain.
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// OptionalAuth adds claims to the request context when a valid token is
+// present, but lets anonymous requests through. Handlers must cope with
+// missing claims.
+func (a *Authenticator) OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, err := tokenFromRequest(r)
+		if err == nil {
+			if claims, err := a.tokens.ValidateJWT(tokenString); err == nil && a.validateSession(r.Context(), claims) == nil && a.accounts.ValidateAccount(r.Context(), claims.UserID) == nil {
+				r = r.WithContext(context.WithValue(r.Context(), ClaimsKey, claims))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validateSession checks the token's session. Tokens issued without a session
+// are rejected.
+func (a *Authenticator) validateSession(ctx context.Context, claims *auth.Claims) error {
+	if claims.SessionID.IsZero() {
+		return errors.New("token has no session")
+	}
+	return a.sessions.ValidateSession(ctx, claims.SessionID)
+}
+
+// tokenFromRequest returns the token from the HttpOnly cookie, falling back to
+// the Authorization header.
+func tokenFromRequest(r *http.Request) (string, error) {
+	if cookie, err := r.Cookie("token"); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", errNoToken
+	}
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", errMalformedBearer
+	}
+	return parts[1], nil
+}
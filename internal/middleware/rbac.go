@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+)
+
+// RequireRole only lets requests through whose role is at least the given one
+// in the role hierarchy. It must be layered on Auth. Roles that require
+// two-factor authentication must also have signed in with it.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return authorize(func(claims *auth.Claims) bool {
+		return auth.HasRole(claims.Role, role)
+	})
+}
+
+// RequirePermission only lets requests through whose role has been granted the
+// permission. It must be layered on Auth.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return authorize(func(claims *auth.Claims) bool {
+		return auth.Can(claims.Role, permission)
+	})
+}
+
+func authorize(allowed func(*auth.Claims) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(ClaimsKey).(*auth.Claims)
+			if !ok || claims == nil {
+				log.Println("Auth Error: authorization checked without authenticated claims")
+				http.Error(w, "Missing authorization token", http.StatusUnauthorized)
+				return
+			}
+			if !allowed(claims) {
+				log.Printf("Auth Error: user %s with role %q denied access to %s", claims.UserID.Hex(), claims.Role, r.URL.Path)
+				http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+				return
+			}
+			if !claims.TwoFactorSatisfied() {
+				log.Printf("Auth Error: user %s with role %q needs two-factor authentication for %s", claims.UserID.Hex(), claims.Role, r.URL.Path)
+				http.Error(w, "Forbidden: two-factor authentication required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// claimsFor returns claims as Auth would store them for a token issued to
+// role after amr.
+func claimsFor(t *testing.T, tokens *auth.Tokens, role string, amr ...string) *auth.Claims {
+	t.Helper()
+	signed, err := tokens.GenerateJWT(primitive.NewObjectID(), "user@unsrat.ac.id", role, primitive.NewObjectID(), amr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tokens.ValidateJWT(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestRequirePermission(t *testing.T) {
+	tokens, err := auth.NewTokens(config.Config{
+		JWTIssuer:              "jte-ticketing",
+		JWTAudience:            "jte-ticketing",
+		AccessTokenTTL:         time.Minute,
+		TwoFactorRequiredRoles: []string{auth.RoleSuperAdmin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims *auth.Claims
+		want   int
+	}{
+		{"no claims", nil, http.StatusUnauthorized},
+		{"student", claimsFor(t, tokens, auth.RoleStudent, auth.AMRPassword), http.StatusForbidden},
+		{"admin", claimsFor(t, tokens, auth.RoleAdmin, auth.AMRPassword), http.StatusOK},
+		{"superadmin without second factor", claimsFor(t, tokens, auth.RoleSuperAdmin, auth.AMRPassword), http.StatusForbidden},
+		{"superadmin with second factor", claimsFor(t, tokens, auth.RoleSuperAdmin, auth.AMRPassword, auth.AMROTP), http.StatusOK},
+		{"unknown role", claimsFor(t, tokens, "root", auth.AMRPassword, auth.AMROTP), http.StatusForbidden},
+	}
+	handler := RequirePermission(auth.PermManageUsers)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/users", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), ClaimsKey, tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status: got %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Asset unit statuses. A unit is available until it is handed over on a
+// loan, sent to repair or retired.
+const (
+	AssetUnitAvailable = "available"
+	AssetUnitOnLoan    = "on_loan"
+	AssetUnitInRepair  = "in_repair"
+	AssetUnitRetired   = "retired"
+)
+
+// Asset unit history event types.
+const (
+	AssetUnitEventCreated          = "created"
+	AssetUnitEventLoaned           = "loaned"
+	AssetUnitEventReturned         = "returned"
+	AssetUnitEventRepairStarted    = "repair_started"
+	AssetUnitEventRepairFinished   = "repair_finished"
+	AssetUnitEventConditionChanged = "condition_changed"
+	AssetUnitEventRetired          = "retired"
+)
+
+// AssetUnit is one physical, individually tagged unit of an inventory item,
+// such as a single laptop.
+type AssetUnit struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ItemID       primitive.ObjectID `bson:"item_id" json:"itemId"`
+	AssetTag     string             `bson:"asset_tag" json:"assetTag"` // printed on the label, e.g. "LPT-0001"
+	SerialNumber string             `bson:"serial_number,omitempty" json:"serialNumber,omitempty"`
+	Status       string             `bson:"status" json:"status"`
+	Condition    string             `bson:"condition" json:"condition"` // one of the ItemCondition constants
+	Notes        string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updatedAt"`
+
+	// CurrentLoanID is the loan the unit is out on while it is on_loan.
+	CurrentLoanID *primitive.ObjectID `bson:"current_loan_id,omitempty" json:"currentLoanId,omitempty"`
+	RetiredAt     *time.Time          `bson:"retired_at,omitempty" json:"retiredAt,omitempty"`
+}
+
+// AssetUnitEvent is an entry in a unit's history.
+type AssetUnitEvent struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UnitID    primitive.ObjectID  `bson:"unit_id" json:"unitId"`
+	Type      string              `bson:"type" json:"type"`
+	LoanID    *primitive.ObjectID `bson:"loan_id,omitempty" json:"loanId,omitempty"`
+	Condition string              `bson:"condition,omitempty" json:"condition,omitempty"`
+	Notes     string              `bson:"notes,omitempty" json:"notes,omitempty"`
+	ActorID   *primitive.ObjectID `bson:"actor_id,omitempty" json:"actorId,omitempty"` // the admin who recorded it
+	CreatedAt time.Time           `bson:"created_at" json:"createdAt"`
+}
+
+// AssetUnitDetail is a unit with its history, newest first.
+type AssetUnitDetail struct {
+	AssetUnit
+	History []AssetUnitEvent `json:"history"`
+}
+
+// CreateAssetUnitPayload is the body accepted when tagging a new unit.
+// Condition defaults to good.
+type CreateAssetUnitPayload struct {
+	AssetTag     string `json:"assetTag"`
+	SerialNumber string `json:"serialNumber"`
+	Condition    string `json:"condition"`
+	Notes        string `json:"notes"`
+}
+
+// UpdateAssetUnitPayload is the body accepted when editing a unit. Fields
+// left out are unchanged; an empty serialNumber or notes clears it.
+type UpdateAssetUnitPayload struct {
+	SerialNumber *string `json:"serialNumber"`
+	Condition    *string `json:"condition"`
+	Notes        *string `json:"notes"`
+}
+
+// AssetUnitActionPayload is the body accepted by the repair and retire
+// endpoints. Condition is only used when a repair is finished.
+type AssetUnitActionPayload struct {
+	Condition string `json:"condition"`
+	Notes     string `json:"notes"`
+}
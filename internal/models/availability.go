@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of busy intervals returned by the availability endpoint.
+const (
+	BusyReservation = "reservation"
+	BusyPending     = "pending"
+	BusyMaintenance = "maintenance"
+	BusyBlackout    = "blackout"
+)
+
+// TimeInterval is a half-open time range [Start, End).
+type TimeInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// BusyInterval is a time range during which a room cannot be booked.
+type BusyInterval struct {
+	Start         time.Time           `json:"start"`
+	End           time.Time           `json:"end"`
+	Kind          string              `json:"kind"`
+	ReservationID *primitive.ObjectID `json:"reservationId,omitempty"`
+	BlackoutID    *primitive.ObjectID `json:"blackoutId,omitempty"`
+	Title         string              `json:"title,omitempty"`
+}
+
+// RoomAvailability is the free/busy calendar of a room within a requested range.
+type RoomAvailability struct {
+	RoomID primitive.ObjectID `json:"roomId"`
+	From   time.Time          `json:"from"`
+	To     time.Time          `json:"to"`
+	Busy   []BusyInterval     `json:"busy"`
+	Free   []TimeInterval     `json:"free"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Blackout scopes.
+const (
+	BlackoutGlobal   = "global"
+	BlackoutBuilding = "building"
+	BlackoutRoom     = "room"
+)
+
+// Blackout is a closure (holiday, event, building works) during which rooms
+// cannot be reserved. It applies to every room, to the rooms of one building,
+// or to a single room, and repeats when Recurrence holds an RRULE.
+type Blackout struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Title      string              `bson:"title" json:"title"`
+	Reason     string              `bson:"reason,omitempty" json:"reason,omitempty"`
+	Scope      string              `bson:"scope" json:"scope"`
+	Building   string              `bson:"building,omitempty" json:"building,omitempty"`
+	RoomID     *primitive.ObjectID `bson:"room_id,omitempty" json:"roomId,omitempty"`
+	StartTime  time.Time           `bson:"start_time" json:"startTime"` // first occurrence
+	EndTime    time.Time           `bson:"end_time" json:"endTime"`
+	Recurrence string              `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
+	CreatedBy  primitive.ObjectID  `bson:"created_by" json:"createdBy"`
+	CreatedAt  time.Time           `bson:"created_at" json:"createdAt"`
+}
+
+// BlackoutPayload is the body for creating or updating a blackout.
+type BlackoutPayload struct {
+	Title      string `json:"title"`
+	Reason     string `json:"reason"`
+	Scope      string `json:"scope"`
+	Building   string `json:"building"`
+	RoomID     string `json:"roomId"`
+	StartTime  string `json:"startTime"`
+	EndTime    string `json:"endTime"`
+	Recurrence string `json:"recurrence"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PolicyWildcard matches any room type or role in a BookingPolicy.
+const PolicyWildcard = "*"
+
+// BookingPolicy restricts who may book a room type and how. Zero limits mean
+// "no limit" and empty allowed hours mean "any time of day".
+type BookingPolicy struct {
+	ID                    primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	RoomType              string              `bson:"room_type" json:"roomType"` // "high", "medium", "low" or "*"
+	Role                  string              `bson:"role" json:"role"`          // "student", "admin", "superadmin" or "*"
+	AllowBooking          bool                `bson:"allow_booking" json:"allowBooking"`
+	MaxDurationMinutes    int                 `bson:"max_duration_minutes" json:"maxDurationMinutes"`
+	MinLeadMinutes        int                 `bson:"min_lead_minutes" json:"minLeadMinutes"`
+	MaxAdvanceDays        int                 `bson:"max_advance_days" json:"maxAdvanceDays"`
+	AllowedFrom           string              `bson:"allowed_from,omitempty" json:"allowedFrom,omitempty"`   // "HH:MM", local time
+	AllowedUntil          string              `bson:"allowed_until,omitempty" json:"allowedUntil,omitempty"` // "HH:MM", local time
+	MaxActiveReservations int                 `bson:"max_active_reservations" json:"maxActiveReservations"`
+	UpdatedAt             time.Time           `bson:"updated_at" json:"updatedAt"`
+	UpdatedBy             *primitive.ObjectID `bson:"updated_by,omitempty" json:"updatedBy,omitempty"`
+}
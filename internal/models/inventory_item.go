@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Item conditions, as last inspected by an admin.
+const (
+	ItemConditionGood    = "good"
+	ItemConditionFair    = "fair"
+	ItemConditionDamaged = "damaged"
+)
+
+// InventoryItem is a kind of item the department lends out, e.g. a projector
+// model of which it owns TotalQuantity units.
+type InventoryItem struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Code          string             `bson:"code" json:"code"`
+	Name          string             `bson:"name" json:"name"`
+	Category      string             `bson:"category" json:"category"`
+	Description   string             `bson:"description,omitempty" json:"description,omitempty"`
+	TotalQuantity int                `bson:"total_quantity" json:"totalQuantity"`
+	Location      string             `bson:"location" json:"location"`
+	Condition     string             `bson:"condition" json:"condition"`
+	ImageURL      string             `bson:"image_url,omitempty" json:"imageUrl"`
+	CreatedAt     time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updatedAt"`
+
+	// AvailableQuantity is derived when items are read: the units that can
+	// be lent, which for items with tagged units excludes those in repair,
+	// retired or damaged, minus the units held by active loans.
+	AvailableQuantity int `bson:"-" json:"availableQuantity"`
+
+	// DeletedAt marks a soft-deleted item. Deleted items are hidden from the
+	// catalog, but past loan requests still reference them.
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
+}
+
+// InventoryItemPayload is the body for creating or updating an item.
+type InventoryItemPayload struct {
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	Category      string `json:"category"`
+	Description   string `json:"description"`
+	TotalQuantity int    `json:"totalQuantity"`
+	Location      string `json:"location"`
+	Condition     string `json:"condition"`
+	ImageURL      string `json:"imageUrl"`
+}
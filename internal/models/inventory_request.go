@@ -6,6 +6,23 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Inventory request statuses. A loan starts out Pending and is moved to
+// Approved or Rejected by an admin; an Approved loan becomes Picked Up when
+// the items are handed over and Returned when they come back. A Picked Up
+// loan still out after its period is marked Overdue by the scheduler.
+const (
+	InventoryRequestPending  = "Pending"
+	InventoryRequestApproved = "Approved"
+	InventoryRequestRejected = "Rejected"
+	InventoryRequestPickedUp = "Picked Up"
+	InventoryRequestReturned = "Returned"
+	InventoryRequestOverdue  = "Overdue"
+)
+
+// InventoryHoldingStatuses are the statuses in which a request holds units
+// of its item, making them unavailable to others.
+var InventoryHoldingStatuses = []string{InventoryRequestApproved, InventoryRequestPickedUp, InventoryRequestOverdue}
+
 // InventoryRequest represents a request for an inventory item.
 // This will be used for the table on the /status page.
 type InventoryRequest struct {
@@ -16,4 +33,66 @@ type InventoryRequest struct {
 	RequestDate   time.Time          `bson:"request_date" json:"request_date"`
 	Status        string             `bson:"status" json:"status"` // e.g., "Approved", "Pending", "Rejected"
 	PickupDate    time.Time          `bson:"pickup_date" json:"pickup_date"`
+
+	// ItemID links the request to a catalog item. Requests made before the
+	// catalog existed only have ItemName.
+	ItemID   *primitive.ObjectID `bson:"item_id,omitempty" json:"item_id,omitempty"`
+	Quantity int                 `bson:"quantity,omitempty" json:"quantity,omitempty"`
+
+	// Loan fields are set on requests made through the loan endpoints. The
+	// units are needed in the half-open interval [NeededFrom, NeededUntil).
+	UserID      *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Purpose     string              `bson:"purpose,omitempty" json:"purpose,omitempty"`
+	NeededFrom  *time.Time          `bson:"needed_from,omitempty" json:"needed_from,omitempty"`
+	NeededUntil *time.Time          `bson:"needed_until,omitempty" json:"needed_until,omitempty"`
+
+	// Decision fields are set when an admin approves or rejects the request.
+	DecidedBy      *primitive.ObjectID `bson:"decided_by,omitempty" json:"decided_by,omitempty"`
+	DecidedAt      *time.Time          `bson:"decided_at,omitempty" json:"decided_at,omitempty"`
+	DecisionReason string              `bson:"decision_reason,omitempty" json:"decision_reason,omitempty"`
+
+	// Handover fields are set when the items are picked up and returned.
+	PickedUpAt      *time.Time          `bson:"picked_up_at,omitempty" json:"picked_up_at,omitempty"`
+	PickedUpBy      *primitive.ObjectID `bson:"picked_up_by,omitempty" json:"picked_up_by,omitempty"`
+	PickupNotes     string              `bson:"pickup_notes,omitempty" json:"pickup_notes,omitempty"`
+	ReturnedAt      *time.Time          `bson:"returned_at,omitempty" json:"returned_at,omitempty"`
+	ReturnedTo      *primitive.ObjectID `bson:"returned_to,omitempty" json:"returned_to,omitempty"`
+	ReturnCondition string              `bson:"return_condition,omitempty" json:"return_condition,omitempty"`
+	ReturnNotes     string              `bson:"return_notes,omitempty" json:"return_notes,omitempty"`
+
+	// UnitIDs are the tagged units handed over at pickup, for items whose
+	// units are tracked individually.
+	UnitIDs []primitive.ObjectID `bson:"unit_ids,omitempty" json:"unit_ids,omitempty"`
+
+	// OverdueAt is set when the scheduler marks the loan Overdue, and
+	// OverdueNotifiedAt once the borrower has been notified.
+	// OverdueNotifyAttempts counts delivery attempts, and
+	// OverdueNotifyClaimedUntil is set while a run is delivering.
+	OverdueAt                 *time.Time `bson:"overdue_at,omitempty" json:"overdue_at,omitempty"`
+	OverdueNotifiedAt         *time.Time `bson:"overdue_notified_at,omitempty" json:"overdue_notified_at,omitempty"`
+	OverdueNotifyAttempts     int        `bson:"overdue_notify_attempts,omitempty" json:"-"`
+	OverdueNotifyClaimedUntil *time.Time `bson:"overdue_notify_claimed_until,omitempty" json:"-"`
+}
+
+// CreateLoanPayload is the body accepted when requesting to borrow an item.
+type CreateLoanPayload struct {
+	ItemID      string `json:"itemId"`
+	Quantity    int    `json:"quantity"`
+	NeededFrom  string `json:"neededFrom"`
+	NeededUntil string `json:"neededUntil"`
+	Purpose     string `json:"purpose"`
+}
+
+// LoanDecisionPayload is the body accepted by the approve and reject endpoints.
+type LoanDecisionPayload struct {
+	Reason string `json:"reason"`
+}
+
+// LoanHandoverPayload is the body accepted when recording a pickup or a
+// return. Condition is only used on return and defaults to good; UnitIDs is
+// only used on pickup and names the tagged units handed over.
+type LoanHandoverPayload struct {
+	Condition string   `json:"condition"`
+	Notes     string   `json:"notes"`
+	UnitIDs   []string `json:"unitIds"`
 }
@@ -6,10 +6,25 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// LoginLog represents a single login event for a user.
+// Reasons recorded for failed login attempts.
+const (
+	LoginFailureUnknownEmail     = "unknown_email"
+	LoginFailureInvalidPassword  = "invalid_password"
+	LoginFailureEmailNotVerified = "email_not_verified"
+	LoginFailureThrottled        = "throttled"
+	LoginFailureInvalidTwoFactor = "invalid_two_factor"
+	LoginFailureAccountDisabled  = "account_disabled"
+)
+
+// LoginLog represents a single login attempt, successful or not.
 type LoginLog struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	UserID    primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"` // empty when the email is unknown
+	Email     string             `bson:"email,omitempty" json:"email,omitempty"`
 	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
 	UserAgent string             `bson:"user_agent" json:"user_agent"`
+	IP        string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	Success   bool               `bson:"success" json:"success"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	SessionID primitive.ObjectID `bson:"session_id,omitempty" json:"session_id,omitempty"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaintenanceWindow is a scheduled period during which a room is under maintenance.
+type MaintenanceWindow struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RoomID    primitive.ObjectID `bson:"room_id" json:"roomId"`
+	Title     string             `bson:"title" json:"title"`
+	Notes     string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	StartTime time.Time          `bson:"start_time" json:"startTime"`
+	EndTime   time.Time          `bson:"end_time" json:"endTime"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"createdBy"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// MaintenanceWindowPayload is the body for scheduling or rescheduling maintenance.
+type MaintenanceWindowPayload struct {
+	Title     string `json:"title"`
+	Notes     string `json:"notes"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification types.
+const (
+	NotificationReservationCancelled = "reservation_cancelled"
+	NotificationReservationReminder  = "reservation_reminder"
+	NotificationLoanOverdue          = "loan_overdue"
+)
+
+// Notification is an in-app message for a user.
+type Notification struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"userId"`
+	Type      string              `bson:"type" json:"type"`
+	Title     string              `bson:"title" json:"title"`
+	Message   string              `bson:"message" json:"message"`
+	RefID     *primitive.ObjectID `bson:"ref_id,omitempty" json:"refId,omitempty"` // e.g. the affected reservation
+	CreatedAt time.Time           `bson:"created_at" json:"createdAt"`
+	ReadAt    *time.Time          `bson:"read_at,omitempty" json:"readAt,omitempty"`
+}
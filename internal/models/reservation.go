@@ -6,6 +6,17 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Reservation statuses. A reservation starts out Pending and is moved to
+// Approved or Rejected by an admin; an Approved reservation can later be Revoked.
+const (
+	ReservationPending  = "Pending"
+	ReservationApproved = "Approved"
+	ReservationRejected = "Rejected"
+	ReservationRevoked  = "Revoked"
+	// ReservationCancelled is set when the requester cancels their own reservation.
+	ReservationCancelled = "Cancelled"
+)
+
 type Reservation struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	RoomID      primitive.ObjectID `bson:"room_id" json:"roomId"`
@@ -16,6 +27,29 @@ type Reservation struct {
 	EndTime     time.Time          `bson:"end_time" json:"endTime"`
 	Status      string             `bson:"status" json:"status"`
 	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+
+	// SeriesID links an occurrence to the ReservationSeries it was expanded from.
+	SeriesID *primitive.ObjectID `bson:"series_id,omitempty" json:"seriesId,omitempty"`
+
+	// Decision fields are set when an admin approves, rejects or revokes the reservation.
+	DecidedBy      *primitive.ObjectID `bson:"decided_by,omitempty" json:"decidedBy,omitempty"`
+	DecidedAt      *time.Time          `bson:"decided_at,omitempty" json:"decidedAt,omitempty"`
+	DecisionReason string              `bson:"decision_reason,omitempty" json:"decisionReason,omitempty"`
+
+	CancelledAt *time.Time `bson:"cancelled_at,omitempty" json:"cancelledAt,omitempty"`
+
+	// ReminderSentAt is set once the scheduler has reminded the owner that the
+	// reservation is about to start. ReminderAttempts counts delivery
+	// attempts, and ReminderClaimedUntil is set while a run is delivering.
+	ReminderSentAt       *time.Time `bson:"reminder_sent_at,omitempty" json:"reminderSentAt,omitempty"`
+	ReminderAttempts     int        `bson:"reminder_attempts,omitempty" json:"-"`
+	ReminderClaimedUntil *time.Time `bson:"reminder_claimed_until,omitempty" json:"-"`
+}
+
+// ReservationWithRoom is a reservation with its room embedded, used for detail views.
+type ReservationWithRoom struct {
+	Reservation `bson:",inline"`
+	Room        *Room `bson:"room,omitempty" json:"room,omitempty"`
 }
 
 type CreateReservationPayload struct {
@@ -25,3 +59,26 @@ type CreateReservationPayload struct {
 	StartTime   string `json:"startTime"`
 	EndTime     string `json:"endTime"`
 }
+
+// UpdateReservationPayload is the body accepted when editing a single reservation.
+// Empty fields are left unchanged; changing the time sends the reservation back to Pending.
+type UpdateReservationPayload struct {
+	Purpose     string `json:"purpose"`
+	Description string `json:"description"`
+	StartTime   string `json:"startTime"`
+	EndTime     string `json:"endTime"`
+}
+
+// ReservationConflict describes why a requested time range cannot be booked.
+type ReservationConflict struct {
+	StartTime     time.Time           `json:"startTime"`
+	EndTime       time.Time           `json:"endTime"`
+	ReservationID *primitive.ObjectID `json:"reservationId,omitempty"`
+	BlackoutID    *primitive.ObjectID `json:"blackoutId,omitempty"`
+	Reason        string              `json:"reason"`
+}
+
+// ReservationDecisionPayload is the body accepted by the approve, reject and revoke endpoints.
+type ReservationDecisionPayload struct {
+	Reason string `json:"reason"`
+}
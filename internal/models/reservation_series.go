@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReservationSeries records a recurring booking. Each occurrence is stored as
+// its own Reservation with SeriesID pointing back here, so occurrences can be
+// approved, edited or cancelled individually.
+type ReservationSeries struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RoomID      primitive.ObjectID `bson:"room_id" json:"roomId"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"userId"`
+	Purpose     string             `bson:"purpose" json:"purpose"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	StartTime   time.Time          `bson:"start_time" json:"startTime"` // first occurrence
+	EndTime     time.Time          `bson:"end_time" json:"endTime"`
+	Recurrence  string             `bson:"recurrence" json:"recurrence"` // RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO;UNTIL=20251220"
+	ExDates     []time.Time        `bson:"ex_dates,omitempty" json:"exDates,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// ReservationSeriesWithOccurrences is a series together with its reservations.
+type ReservationSeriesWithOccurrences struct {
+	ReservationSeries `bson:",inline"`
+	Occurrences       []Reservation `json:"occurrences"`
+}
+
+// CreateReservationSeriesPayload is the body for creating a recurring reservation.
+// StartTime and EndTime describe the first occurrence.
+type CreateReservationSeriesPayload struct {
+	CreateReservationPayload
+	Recurrence    string   `json:"recurrence"`
+	ExDates       []string `json:"exDates"`       // YYYY-MM-DD
+	SkipConflicts bool     `json:"skipConflicts"` // create the free occurrences instead of failing
+}
+
+// UpdateReservationSeriesPayload edits every upcoming occurrence of a series.
+// StartTime and EndTime are local times of day ("15:04"). EndTime is optional:
+// without it each occurrence keeps its duration, and an EndTime earlier than
+// StartTime ends the occurrence the next day.
+type UpdateReservationSeriesPayload struct {
+	Purpose     string `json:"purpose"`
+	Description string `json:"description"`
+	StartTime   string `json:"startTime"`
+	EndTime     string `json:"endTime"`
+}
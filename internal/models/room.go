@@ -1,6 +1,19 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Room statuses. Status is derived from maintenance windows and approved
+// reservations when rooms are read, so the stored value is not authoritative.
+const (
+	RoomAvailable        = "Available"
+	RoomInUse            = "In Use"
+	RoomUnderMaintenance = "Under Maintenance"
+)
 
 type Facility struct {
 	FurnitureAvailable bool `bson:"furniture_available" json:"furnitureAvailable"`
@@ -19,4 +32,26 @@ type Room struct {
 	Location string             `bson:"location" json:"location"`
 	Type     string             `bson:"type" json:"type"`
 	Facility Facility           `bson:"facility,omitempty" json:"facility,omitempty"`
+
+	// DeletedAt marks a soft-deleted room. Deleted rooms are hidden from the
+	// catalog and cannot be booked, but past reservations still reference them.
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
+}
+
+// RoomPayload is the body for creating or updating a room.
+type RoomPayload struct {
+	RoomID   string    `json:"room_id"`
+	Name     string    `json:"name"`
+	ImageURL string    `json:"imageUrl"`
+	Capacity int       `json:"capacity"`
+	Location string    `json:"location"`
+	Type     string    `json:"type"`
+	Facility *Facility `json:"facility"`
+}
+
+// Building returns the building part of Location, e.g. "Gedung Jurusan Teknik
+// Elektro" for "Gedung Jurusan Teknik Elektro, Lantai 1".
+func (r Room) Building() string {
+	building, _, _ := strings.Cut(r.Location, ",")
+	return strings.TrimSpace(building)
 }
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session revocation reasons.
+const (
+	SessionRevokedLogout    = "logout"
+	SessionRevokedReuse     = "refresh_token_reuse"
+	SessionRevokedByUser    = "revoked_by_user"
+	SessionRevokedLogoutAll = "logout_everywhere"
+	SessionRevokedByAdmin   = "revoked_by_admin"
+	SessionRevokedPassword  = "password_reset"
+	SessionRevokedDisabled  = "account_disabled"
+	SessionRevokedRole      = "role_changed"
+	SessionRevokedDeleted   = "account_deleted"
+)
+
+// Session is one login of a user. It holds the refresh token family: every
+// refresh rotates CurrentTokenHash and keeps the old hash in
+// PreviousTokenHashes so that replaying a rotated token can be detected.
+type Session struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID              primitive.ObjectID `bson:"user_id" json:"userId"`
+	CurrentTokenHash    string             `bson:"current_token_hash" json:"-"`
+	PreviousTokenHashes []string           `bson:"previous_token_hashes" json:"-"`
+	UserAgent           string             `bson:"user_agent" json:"userAgent"`
+	IP                  string             `bson:"ip" json:"ip"`
+	AMR                 []string           `bson:"amr,omitempty" json:"amr,omitempty"`
+	CreatedAt           time.Time          `bson:"created_at" json:"createdAt"`
+	LastSeenAt          time.Time          `bson:"last_seen_at" json:"lastSeenAt"`
+	ExpiresAt           time.Time          `bson:"expires_at" json:"expiresAt"`
+	RevokedAt           *time.Time         `bson:"revoked_at,omitempty" json:"revokedAt,omitempty"`
+	RevokedReason       string             `bson:"revoked_reason,omitempty" json:"revokedReason,omitempty"`
+}
+
+// RefreshTokenPayload carries a refresh token for clients that cannot use the
+// refresh_token cookie.
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// SessionInfo is a session as shown to its user.
+type SessionInfo struct {
+	ID         primitive.ObjectID `json:"id"`
+	Device     string             `json:"device"`
+	UserAgent  string             `json:"userAgent"`
+	IP         string             `json:"ip"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	LastSeenAt time.Time          `json:"lastSeenAt"`
+	ExpiresAt  time.Time          `json:"expiresAt"`
+	Current    bool               `json:"current"`
+}
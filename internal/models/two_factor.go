@@ -0,0 +1,21 @@
+package models
+
+// TwoFactorCodePayload carries a TOTP code from the user's authenticator app.
+type TwoFactorCodePayload struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorLoginPayload finishes a two-step login.
+type TwoFactorLoginPayload struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recoveryCode,omitempty"`
+}
+
+// DisableTwoFactorPayload turns two-factor authentication off. Both the
+// password and a code (or recovery code) are required.
+type DisableTwoFactorPayload struct {
+	Password     string `json:"password"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
@@ -1,13 +1,57 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User types describe a person's place in the department, independent of
+// their role in the application.
+const (
+	UserTypeStudent  = "student"
+	UserTypeLecturer = "lecturer"
+	UserTypeStaff    = "staff"
+)
 
 // User represents a user in the database.
 type User struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Email    string             `bson:"email" json:"email"`
-	Password string             `bson:"password" json:"password"`
-	Role     string             `bson:"role" json:"role"` // Added Role field (e.g., "admin", "student")
+	Password string             `bson:"password" json:"-"` // bcrypt hash, never sent to clients
+	Role     string             `bson:"role" json:"role"`  // Added Role field (e.g., "admin", "student")
+
+	// Academic identity. IdentityNumber is the NIM for students and the NIP
+	// for lecturers and staff. Users may edit FullName, Phone and AvatarURL
+	// themselves; the rest is maintained by admins.
+	FullName       string     `bson:"full_name,omitempty" json:"fullName"`
+	UserType       string     `bson:"user_type,omitempty" json:"userType"`
+	IdentityNumber string     `bson:"identity_number,omitempty" json:"identityNumber"`
+	Phone          string     `bson:"phone,omitempty" json:"phone"`
+	StudyProgram   string     `bson:"study_program,omitempty" json:"studyProgram"`
+	Faculty        string     `bson:"faculty,omitempty" json:"faculty"`
+	AvatarURL      string     `bson:"avatar_url,omitempty" json:"avatarUrl"`
+	UpdatedAt      *time.Time `bson:"updated_at,omitempty" json:"updatedAt,omitempty"`
+
+	// Disabled accounts cannot log in and their tokens are rejected. Deleted
+	// accounts are anonymized and stay disabled; the document is kept so
+	// reservations and logs still point somewhere.
+	Disabled       bool       `bson:"disabled,omitempty" json:"disabled"`
+	DisabledAt     *time.Time `bson:"disabled_at,omitempty" json:"disabledAt,omitempty"`
+	DisabledReason string     `bson:"disabled_reason,omitempty" json:"disabledReason,omitempty"`
+	DeletedAt      *time.Time `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
+
+	EmailVerified   bool       `bson:"email_verified" json:"emailVerified"`
+	EmailVerifiedAt *time.Time `bson:"email_verified_at,omitempty" json:"emailVerifiedAt,omitempty"`
+
+	// Two-factor authentication. The secret is only moved from
+	// TOTPPendingSecret to TOTPSecret once the user proved they can generate
+	// codes. TOTPLastStep is the last accepted time step, so codes cannot be replayed.
+	TOTPEnabled        bool     `bson:"totp_enabled" json:"totpEnabled"`
+	TOTPSecret         string   `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret  string   `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPLastStep       int64    `bson:"totp_last_step,omitempty" json:"-"`
+	RecoveryCodeHashes []string `bson:"recovery_code_hashes,omitempty" json:"-"`
 }
 
 // Credentials is used for parsing login and registration requests.
@@ -15,3 +59,42 @@ type Credentials struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+// ProfilePayload is the body for PATCH /api/me and the admin profile edit.
+// Omitted fields are left unchanged; an empty string clears a field.
+type ProfilePayload struct {
+	FullName  *string `json:"fullName"`
+	Phone     *string `json:"phone"`
+	AvatarURL *string `json:"avatarUrl"`
+
+	// Only admins may change these.
+	UserType       *string `json:"userType"`
+	IdentityNumber *string `json:"identityNumber"`
+	StudyProgram   *string `json:"studyProgram"`
+	Faculty        *string `json:"faculty"`
+}
+
+// ChangeRolePayload is the body for changing a user's role.
+type ChangeRolePayload struct {
+	Role string `json:"role"`
+}
+
+// DisableUserPayload is the body for disabling an account.
+type DisableUserPayload struct {
+	Reason string `json:"reason"`
+}
+
+// UserList is one page of users in the admin user search.
+type UserList struct {
+	Users []User `json:"users"`
+	Page  int    `json:"page"`
+	Limit int    `json:"limit"`
+	Total int64  `json:"total"`
+}
+
+// UserDetail is a user as shown to admins, with recent activity.
+type UserDetail struct {
+	User         User          `json:"user"`
+	Reservations []Reservation `json:"reservations"`
+	LoginLogs    []LoginLog    `json:"loginLogs"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User token purposes.
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeLoginChallenge    = "login_challenge"
+)
+
+// UserToken is a single-use token mailed to a user, e.g. in a password reset
+// link. Only the hash of the token is stored.
+type UserToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
+	Purpose   string             `bson:"purpose" json:"purpose"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expiresAt"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty" json:"usedAt,omitempty"`
+}
+
+// EmailPayload names an account by email, to start a password reset or
+// resend a verification link.
+type EmailPayload struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordPayload completes a password reset.
+type ResetPasswordPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ChangePasswordPayload changes the caller's password.
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// VerifyEmailPayload completes email verification.
+type VerifyEmailPayload struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,109 @@
+// Package notify delivers notifications to users.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/mail"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Notifier delivers a notification to its user.
+type Notifier interface {
+	Notify(ctx context.Context, n models.Notification) error
+}
+
+// New returns the Notifier delivering through every channel in
+// cfg.NotificationChannels.
+func New(cfg config.Config, db *mongo.Database, mailer mail.Mailer) (Notifier, error) {
+	var notifiers Multi
+	for _, channel := range cfg.NotificationChannels {
+		switch channel {
+		case "inapp":
+			notifiers = append(notifiers, NewInApp(db))
+		case "mail":
+			notifiers = append(notifiers, NewMail(db, mailer))
+		default:
+			return nil, fmt.Errorf("notify: unknown channel %q", channel)
+		}
+	}
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
+	}
+	return notifiers, nil
+}
+
+// InApp stores notifications in the notifications collection, where users read
+// them through the notifications API.
+type InApp struct {
+	db *mongo.Database
+}
+
+// NewInApp creates an in-app Notifier.
+func NewInApp(db *mongo.Database) *InApp {
+	return &InApp{db: db}
+}
+
+// Notify stores the notification.
+func (n *InApp) Notify(ctx context.Context, notification models.Notification) error {
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	_, err := n.db.Collection("notifications").InsertOne(ctx, notification)
+	return err
+}
+
+// Mail emails notifications to the address of their user. Deleted accounts
+// are skipped.
+type Mail struct {
+	db     *mongo.Database
+	mailer mail.Mailer
+}
+
+// NewMail creates a Notifier that sends email through mailer.
+func NewMail(db *mongo.Database, mailer mail.Mailer) *Mail {
+	return &Mail{db: db, mailer: mailer}
+}
+
+// Notify emails the notification.
+func (n *Mail) Notify(ctx context.Context, notification models.Notification) error {
+	var user models.User
+	findOptions := options.FindOne().SetProjection(bson.M{"email": 1, "deleted_at": 1})
+	if err := n.db.Collection("users").FindOne(ctx, bson.M{"_id": notification.UserID}, findOptions).Decode(&user); err != nil {
+		return fmt.Errorf("notify: looking up user %s: %w", notification.UserID.Hex(), err)
+	}
+	if user.DeletedAt != nil {
+		return nil
+	}
+	return n.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: notification.Title,
+		Body:    notification.Message,
+	})
+}
+
+// Multi delivers each notification through every notifier in turn. All of
+// them are tried even if one fails.
+type Multi []Notifier
+
+// Notify delivers the notification through every notifier.
+func (m Multi) Notify(ctx context.Context, notification models.Notification) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
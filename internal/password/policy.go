@@ -0,0 +1,149 @@
+// Package password checks new passwords against the configured policy and
+// hashes them with bcrypt.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// commonPasswords is the built-in blocklist, compiled into the binary so the
+// server does not depend on the directory it is started from.
+//
+//go:embed common-passwords.txt
+var commonPasswords string
+
+// maxLength is bcrypt's input limit; longer passwords would be silently truncated.
+const maxLength = 72
+
+// Policy describes which passwords are accepted.
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	Cost          int
+
+	blocked map[string]bool
+}
+
+// LoadPolicy builds the policy from cfg. The built-in blocklist always
+// applies; cfg.PasswordBlocklistFile can name a file with more passwords.
+func LoadPolicy(cfg config.Config) (*Policy, error) {
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("password: BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if cfg.PasswordMinLength < 1 || cfg.PasswordMinLength > maxLength {
+		return nil, fmt.Errorf("password: PASSWORD_MIN_LENGTH must be between 1 and %d", maxLength)
+	}
+
+	policy := &Policy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		Cost:          cfg.BcryptCost,
+		blocked:       map[string]bool{},
+	}
+	if err := policy.loadBlocklist(strings.NewReader(commonPasswords)); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordBlocklistFile != "" {
+		f, err := os.Open(cfg.PasswordBlocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("password: reading blocklist: %w", err)
+		}
+		defer f.Close()
+		if err := policy.loadBlocklist(f); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// loadBlocklist reads one password per line. Blank lines and lines starting
+// with # are ignored. Entries are compared case-insensitively.
+func (p *Policy) loadBlocklist(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocked[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("password: reading blocklist: %w", err)
+	}
+	return nil
+}
+
+// Validate returns every rule the password breaks, or nil when it is
+// acceptable. The account email is used to reject passwords built from it.
+func (p *Policy) Validate(password, email string) []string {
+	var problems []string
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("Password must be at least %d characters", p.MinLength))
+	}
+	if len(password) > maxLength {
+		problems = append(problems, fmt.Sprintf("Password must be at most %d bytes", maxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		problems = append(problems, "Password must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "Password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "Password must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "Password must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if p.blocked[lowered] {
+		problems = append(problems, "Password is too common")
+	}
+	if local, _, found := strings.Cut(strings.ToLower(email), "@"); found && len(local) >= 4 && strings.Contains(lowered, local) {
+		problems = append(problems, "Password must not contain your email name")
+	}
+
+	return problems
+}
+
+// Hash hashes the password with the policy's bcrypt cost.
+func (p *Policy) Hash(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), p.Cost)
+}
+
+// NeedsRehash reports whether a stored hash uses a lower cost than the policy.
+func (p *Policy) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err == nil && cost < p.Cost
+}
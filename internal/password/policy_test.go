@@ -0,0 +1,143 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testPolicy(t *testing.T, cfg config.Config) *Policy {
+	t.Helper()
+	if cfg.PasswordMinLength == 0 {
+		cfg.PasswordMinLength = 8
+	}
+	if cfg.BcryptCost == 0 {
+		cfg.BcryptCost = bcrypt.MinCost
+	}
+	policy, err := LoadPolicy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func TestValidate(t *testing.T) {
+	policy := testPolicy(t, config.Config{
+		PasswordRequireLower: true,
+		PasswordRequireDigit: true,
+	})
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		problem  string // empty when the password is acceptable
+	}{
+		{"acceptable", "kopi susu 42", "student@unsrat.ac.id", ""},
+		{"too short", "kopi42", "", "at least 8 characters"},
+		{"length counts characters, not bytes", "kopiñña4", "", ""},
+		{"longer than bcrypt accepts", strings.Repeat("kopi4", 15), "", "at most 72 bytes"},
+		{"no digit", "kopisusu", "", "a digit"},
+		{"no lowercase letter", "KOPISUSU42", "", "a lowercase letter"},
+		{"embedded blocklist", "password123", "", "too common"},
+		{"blocklist ignores case", "Password123", "", "too common"},
+		{"contains email name", "xx-mario.p-42", "mario.p@unsrat.ac.id", "your email name"},
+		{"email name ignores case", "xxMARIO.P42", "Mario.P@unsrat.ac.id", "your email name"},
+		{"short email names are not checked", "joe-kopi-42", "joe@unsrat.ac.id", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := policy.Validate(tt.password, tt.email)
+			if tt.problem == "" {
+				if len(problems) > 0 {
+					t.Errorf("got problems %q, want none", problems)
+				}
+				return
+			}
+			if !strings.Contains(strings.Join(problems, "\n"), tt.problem) {
+				t.Errorf("got problems %q, want one about %q", problems, tt.problem)
+			}
+		})
+	}
+}
+
+func TestValidateOptionalRules(t *testing.T) {
+	policy := testPolicy(t, config.Config{PasswordRequireUpper: true, PasswordRequireSymbol: true})
+
+	problems := strings.Join(policy.Validate("kopisusu42", ""), "\n")
+	for _, want := range []string{"an uppercase letter", "a symbol"} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("got problems %q, want one about %q", problems, want)
+		}
+	}
+	if problems := policy.Validate("Kopi-Susu42", ""); len(problems) > 0 {
+		t.Errorf("got problems %q, want none", problems)
+	}
+}
+
+func TestLoadPolicyAddsBlocklistFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("# campus passwords\n\nUnsrat2024\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy := testPolicy(t, config.Config{PasswordBlocklistFile: path})
+
+	for _, password := range []string{"unsrat2024", "password123"} {
+		if !strings.Contains(strings.Join(policy.Validate(password, ""), "\n"), "too common") {
+			t.Errorf("%q was not rejected as too common", password)
+		}
+	}
+	if policy.blocked["# campus passwords"] {
+		t.Error("comment line was added to the blocklist")
+	}
+}
+
+func TestLoadPolicyRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+	}{
+		{"cost too low", config.Config{PasswordMinLength: 8, BcryptCost: bcrypt.MinCost - 1}},
+		{"cost too high", config.Config{PasswordMinLength: 8, BcryptCost: bcrypt.MaxCost + 1}},
+		{"no minimum length", config.Config{PasswordMinLength: 0, BcryptCost: bcrypt.MinCost}},
+		{"minimum above bcrypt's limit", config.Config{PasswordMinLength: 73, BcryptCost: bcrypt.MinCost}},
+		{"missing blocklist file", config.Config{PasswordMinLength: 8, BcryptCost: bcrypt.MinCost, PasswordBlocklistFile: "does-not-exist.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadPolicy(tt.cfg); err == nil {
+				t.Error("policy was loaded")
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	policy := testPolicy(t, config.Config{BcryptCost: bcrypt.MinCost + 1})
+
+	weak, err := bcrypt.GenerateFromPassword([]byte("kopi susu 42"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.NeedsRehash(weak) {
+		t.Error("hash below the policy cost was not flagged for rehashing")
+	}
+
+	current, err := policy.Hash("kopi susu 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.NeedsRehash(current) {
+		t.Error("hash at the policy cost was flagged for rehashing")
+	}
+	if policy.NeedsRehash([]byte("not a bcrypt hash")) {
+		t.Error("invalid hash was flagged for rehashing")
+	}
+	if err := bcrypt.CompareHashAndPassword(current, []byte("kopi susu 42")); err != nil {
+		t.Errorf("Hash: %v", err)
+	}
+}
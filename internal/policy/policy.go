@@ -0,0 +1,118 @@
+// Package policy evaluates booking policies against a reservation request and
+// reports each violation with a machine-readable code.
+package policy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+)
+
+// Violation codes returned to clients.
+const (
+	CodeRoleNotAllowed     = "ROLE_NOT_ALLOWED"
+	CodeMaxDuration        = "MAX_DURATION_EXCEEDED"
+	CodeMinLeadTime        = "LEAD_TIME_TOO_SHORT"
+	CodeMaxAdvance         = "ADVANCE_WINDOW_EXCEEDED"
+	CodeOutsideHours       = "OUTSIDE_ALLOWED_HOURS"
+	CodeActiveLimitReached = "ACTIVE_RESERVATION_LIMIT"
+)
+
+// Violation is a single broken rule. StartTime identifies the occurrence when
+// several are evaluated at once, as for a recurring series.
+type Violation struct {
+	Code      string     `json:"code"`
+	Message   string     `json:"message"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+}
+
+// Request describes a booking to evaluate.
+type Request struct {
+	Start time.Time
+	End   time.Time
+	Now   time.Time
+	// ActiveReservations is how many upcoming Pending or Approved bookings the
+	// user would hold if this one were accepted. A recurring series counts as
+	// a single booking.
+	ActiveReservations int
+}
+
+// Select returns the policy that applies to a role booking a room type. The most
+// specific match wins: (type, role), then (*, role), then (type, *), then (*, *).
+// It returns false when no policy applies, in which case booking is unrestricted.
+func Select(policies []models.BookingPolicy, roomType, role string) (models.BookingPolicy, bool) {
+	candidates := [][2]string{
+		{roomType, role},
+		{models.PolicyWildcard, role},
+		{roomType, models.PolicyWildcard},
+		{models.PolicyWildcard, models.PolicyWildcard},
+	}
+	for _, candidate := range candidates {
+		for _, p := range policies {
+			if p.RoomType == candidate[0] && p.Role == candidate[1] {
+				return p, true
+			}
+		}
+	}
+	return models.BookingPolicy{}, false
+}
+
+// Evaluate checks a request against a policy. Times of day are compared in loc.
+func Evaluate(p models.BookingPolicy, req Request, loc *time.Location) []Violation {
+	var violations []Violation
+	add := func(code, format string, args ...interface{}) {
+		violations = append(violations, Violation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !p.AllowBooking {
+		add(CodeRoleNotAllowed, "Your role cannot book this type of room")
+		return violations
+	}
+
+	if p.MaxDurationMinutes > 0 && req.End.Sub(req.Start) > time.Duration(p.MaxDurationMinutes)*time.Minute {
+		add(CodeMaxDuration, "Reservations may last at most %d minutes", p.MaxDurationMinutes)
+	}
+	if p.MinLeadMinutes > 0 && req.Start.Sub(req.Now) < time.Duration(p.MinLeadMinutes)*time.Minute {
+		add(CodeMinLeadTime, "Reservations must be made at least %d minutes in advance", p.MinLeadMinutes)
+	}
+	if p.MaxAdvanceDays > 0 && req.Start.After(req.Now.AddDate(0, 0, p.MaxAdvanceDays)) {
+		add(CodeMaxAdvance, "Reservations can be made at most %d days in advance", p.MaxAdvanceDays)
+	}
+	if !withinHours(p, req.Start, req.End, loc) {
+		add(CodeOutsideHours, "Reservations must be between %s and %s", p.AllowedFrom, p.AllowedUntil)
+	}
+	if p.MaxActiveReservations > 0 && req.ActiveReservations > p.MaxActiveReservations {
+		add(CodeActiveLimitReached, "You may hold at most %d active reservations", p.MaxActiveReservations)
+	}
+	return violations
+}
+
+// withinHours reports whether [start, end) lies on a single local day inside
+// the policy's allowed hours.
+func withinHours(p models.BookingPolicy, start, end time.Time, loc *time.Location) bool {
+	if p.AllowedFrom == "" && p.AllowedUntil == "" {
+		return true
+	}
+	from, err := ParseClock(p.AllowedFrom)
+	if err != nil {
+		from = 0
+	}
+	until, err := ParseClock(p.AllowedUntil)
+	if err != nil || p.AllowedUntil == "" {
+		until = 24 * time.Hour
+	}
+
+	localStart, localEnd := start.In(loc), end.In(loc)
+	day := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, loc)
+	return !localStart.Before(day.Add(from)) && !localEnd.After(day.Add(until))
+}
+
+// ParseClock parses a local time of day given as "HH:MM" into an offset from midnight.
+func ParseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
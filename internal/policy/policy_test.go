@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+)
+
+var wita = time.FixedZone("WITA", 8*60*60)
+
+func TestSelect(t *testing.T) {
+	policies := []models.BookingPolicy{
+		{RoomType: models.PolicyWildcard, Role: models.PolicyWildcard, MaxDurationMinutes: 1},
+		{RoomType: "high", Role: models.PolicyWildcard, MaxDurationMinutes: 2},
+		{RoomType: models.PolicyWildcard, Role: "student", MaxDurationMinutes: 3},
+		{RoomType: "high", Role: "student", MaxDurationMinutes: 4},
+	}
+	tests := []struct {
+		roomType, role string
+		want           int
+	}{
+		{"high", "student", 4},
+		{"low", "student", 3},
+		{"high", "admin", 2},
+		{"low", "admin", 1},
+	}
+	for _, tt := range tests {
+		got, found := Select(policies, tt.roomType, tt.role)
+		if !found || got.MaxDurationMinutes != tt.want {
+			t.Errorf("Select(%s, %s) = %+v, %v; want policy %d", tt.roomType, tt.role, got, found, tt.want)
+		}
+	}
+
+	if got, found := Select(policies[1:2], "low", "student"); found {
+		t.Errorf("Select without a match = %+v, want none", got)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2025, 9, 1, 9, 0, 0, 0, wita)
+	at := func(days, hour, minute int) time.Time {
+		return time.Date(2025, 9, 1+days, hour, minute, 0, 0, wita)
+	}
+	p := models.BookingPolicy{
+		AllowBooking:          true,
+		MaxDurationMinutes:    120,
+		MinLeadMinutes:        60,
+		MaxAdvanceDays:        30,
+		AllowedFrom:           "07:00",
+		AllowedUntil:          "21:00",
+		MaxActiveReservations: 2,
+	}
+
+	tests := []struct {
+		name  string
+		p     models.BookingPolicy
+		req   Request
+		codes []string
+	}{
+		{"within limits", p, Request{Start: at(1, 8, 0), End: at(1, 10, 0), Now: now, ActiveReservations: 2}, nil},
+		{"not allowed", models.BookingPolicy{}, Request{Start: at(1, 8, 0), End: at(1, 10, 0), Now: now}, []string{CodeRoleNotAllowed}},
+		{"too long", p, Request{Start: at(1, 8, 0), End: at(1, 10, 1), Now: now}, []string{CodeMaxDuration}},
+		{"too soon", p, Request{Start: at(0, 9, 59), End: at(0, 11, 0), Now: now}, []string{CodeMinLeadTime}},
+		{"too far ahead", p, Request{Start: at(31, 8, 0), End: at(31, 9, 0), Now: now}, []string{CodeMaxAdvance}},
+		{"too early", p, Request{Start: at(1, 6, 30), End: at(1, 7, 30), Now: now}, []string{CodeOutsideHours}},
+		{"too late", p, Request{Start: at(1, 20, 0), End: at(1, 21, 30), Now: now}, []string{CodeOutsideHours}},
+		{"past midnight", p, Request{Start: at(1, 20, 0), End: at(2, 8, 0), Now: now}, []string{CodeMaxDuration, CodeOutsideHours}},
+		{"too many active", p, Request{Start: at(1, 8, 0), End: at(1, 9, 0), Now: now, ActiveReservations: 3}, []string{CodeActiveLimitReached}},
+		{"no limits", models.BookingPolicy{AllowBooking: true}, Request{Start: at(400, 0, 0), End: at(402, 0, 0), Now: now, ActiveReservations: 99}, nil},
+	}
+	for _, tt := range tests {
+		violations := Evaluate(tt.p, tt.req, wita)
+		var codes []string
+		for _, v := range violations {
+			codes = append(codes, v.Code)
+		}
+		if len(codes) != len(tt.codes) {
+			t.Errorf("%s: got %v, want %v", tt.name, codes, tt.codes)
+			continue
+		}
+		for i := range codes {
+			if codes[i] != tt.codes[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, codes, tt.codes)
+				break
+			}
+		}
+	}
+}
+
+func TestEvaluateComparesHoursInLocation(t *testing.T) {
+	p := models.BookingPolicy{AllowBooking: true, AllowedFrom: "07:00", AllowedUntil: "21:00"}
+	// 23:00 UTC is 07:00 the next morning in WITA.
+	start := time.Date(2025, 9, 1, 23, 0, 0, 0, time.UTC)
+	req := Request{Start: start, End: start.Add(time.Hour), Now: start.Add(-24 * time.Hour)}
+	if violations := Evaluate(p, req, wita); len(violations) != 0 {
+		t.Errorf("got %v, want none", violations)
+	}
+}
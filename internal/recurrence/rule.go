@@ -0,0 +1,226 @@
+// Package recurrence implements the subset of iCalendar RRULEs (RFC 5545) used
+// for recurring bookings: FREQ=DAILY or WEEKLY with INTERVAL, BYDAY, UNTIL and COUNT.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported frequencies.
+const (
+	Daily  = "DAILY"
+	Weekly = "WEEKLY"
+)
+
+// MaxOccurrences caps how many occurrences a single rule may expand into.
+const MaxOccurrences = 200
+
+// ErrTooManyOccurrences is returned when a rule expands into more than MaxOccurrences.
+var ErrTooManyOccurrences = fmt.Errorf("recurrence expands into more than %d occurrences", MaxOccurrences)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Until    time.Time // inclusive; zero if unset
+	Count    int       // zero if unset
+}
+
+// Parse parses an RRULE such as "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,WE;UNTIL=20251220".
+// The "RRULE:" prefix is optional. UNTIL accepts a date (YYYYMMDD, taken as
+// the end of that day in loc) or a UTC date-time (YYYYMMDDTHHMMSSZ).
+func Parse(value string, loc *time.Location) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return Rule{}, errors.New("recurrence rule is empty")
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+			if rule.Freq != Daily && rule.Freq != Weekly {
+				return Rule{}, fmt.Errorf("unsupported FREQ %q, use DAILY or WEEKLY", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val, loc)
+			if err != nil {
+				return Rule{}, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := weekdays[strings.ToUpper(code)]
+				if !ok {
+					return Rule{}, fmt.Errorf("invalid BYDAY value %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return Rule{}, errors.New("COUNT and UNTIL cannot be combined")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return Rule{}, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return rule, nil
+}
+
+func parseUntil(val string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", val); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("20060102", val, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid UNTIL %q", val)
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// Bounded reports whether the rule ends, either by COUNT or by UNTIL.
+func (r Rule) Bounded() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// Each calls fn with the start of every occurrence, in order, beginning with
+// dtstart. Occurrences keep the wall-clock time of dtstart in its location.
+// Iteration stops when the rule ends or fn returns false; unbounded rules
+// only stop when fn returns false.
+func (r Rule) Each(dtstart time.Time, fn func(time.Time) bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		if r.Count > 0 && emitted >= r.Count {
+			return false
+		}
+		emitted++
+		return fn(t)
+	}
+
+	if r.Freq == Daily {
+		for i := 0; ; i++ {
+			if !emit(dtstart.AddDate(0, 0, i*interval)) {
+				return
+			}
+		}
+	}
+
+	// Weekly: walk weeks starting on the Monday of dtstart's week and emit the
+	// selected weekdays of every interval-th week.
+	days := r.ByDay
+	if len(days) == 0 {
+		days = []time.Weekday{dtstart.Weekday()}
+	}
+	offsets := make([]int, 0, len(days))
+	for _, d := range days {
+		offsets = append(offsets, (int(d)+6)%7) // Monday = 0
+	}
+	sort.Ints(offsets)
+
+	weekStart := dtstart.AddDate(0, 0, -((int(dtstart.Weekday()) + 6) % 7))
+	for week := 0; ; week += interval {
+		for _, offset := range offsets {
+			t := weekStart.AddDate(0, 0, week*7+offset)
+			if t.Before(dtstart) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// Occurrences expands a bounded rule into occurrence start times, skipping any
+// occurrence that falls on one of the excluded dates (compared as calendar days
+// in dtstart's location). As in RFC 5545, excluded dates still count towards COUNT.
+func (r Rule) Occurrences(dtstart time.Time, exDates []time.Time) ([]time.Time, error) {
+	if !r.Bounded() {
+		return nil, errors.New("recurrence rule must set COUNT or UNTIL")
+	}
+
+	excluded := make(map[string]bool, len(exDates))
+	for _, d := range exDates {
+		excluded[d.In(dtstart.Location()).Format("2006-01-02")] = true
+	}
+
+	var occurrences []time.Time
+	var err error
+	r.Each(dtstart, func(t time.Time) bool {
+		if excluded[t.Format("2006-01-02")] {
+			return true
+		}
+		if len(occurrences) == MaxOccurrences {
+			err = ErrTooManyOccurrences
+			return false
+		}
+		occurrences = append(occurrences, t)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return occurrences, nil
+}
+
+// Overlapping returns the starts of the occurrences, each lasting duration,
+// that intersect [from, to). Unlike Occurrences it also accepts unbounded rules.
+func (r Rule) Overlapping(dtstart time.Time, duration time.Duration, from, to time.Time) []time.Time {
+	var starts []time.Time
+	r.Each(dtstart, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if t.Add(duration).After(from) {
+			starts = append(starts, t)
+		}
+		return true
+	})
+	return starts
+}
@@ -0,0 +1,178 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var wita = time.FixedZone("WITA", 8*60*60)
+
+func TestParse(t *testing.T) {
+	rule, err := Parse("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20251220", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Freq != Weekly || rule.Interval != 2 || rule.Count != 0 {
+		t.Errorf("got %+v", rule)
+	}
+	if len(rule.ByDay) != 2 || rule.ByDay[0] != time.Monday || rule.ByDay[1] != time.Wednesday {
+		t.Errorf("ByDay = %v, want [Monday Wednesday]", rule.ByDay)
+	}
+	// A date UNTIL includes the whole day.
+	wantUntil := time.Date(2025, 12, 20, 23, 59, 59, int(time.Second-time.Nanosecond), wita)
+	if !rule.Until.Equal(wantUntil) {
+		t.Errorf("Until = %v, want %v", rule.Until, wantUntil)
+	}
+
+	rule, err = Parse("freq=daily;count=3;until=20251220T100000Z", wita)
+	if err == nil {
+		t.Errorf("COUNT with UNTIL: got %+v, want an error", rule)
+	}
+
+	rule, err = Parse("FREQ=DAILY;UNTIL=20251220T100000Z", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 12, 20, 10, 0, 0, 0, time.UTC); !rule.Until.Equal(want) {
+		t.Errorf("Until = %v, want %v", rule.Until, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=MONTHLY;COUNT=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;UNTIL=2025-12-20",
+		"FREQ=WEEKLY;BYMONTH=1",
+		"FREQ",
+	} {
+		if rule, err := Parse(value, wita); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", value, rule)
+		}
+	}
+}
+
+func TestOccurrencesWeekly(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wednesday 3 September 2025: the first occurrence is dtstart itself and
+	// the Monday before it is skipped.
+	dtstart := time.Date(2025, 9, 3, 8, 0, 0, 0, wita)
+	exDates := []time.Time{time.Date(2025, 9, 10, 0, 0, 0, 0, wita)}
+
+	got, err := rule.Occurrences(dtstart, exDates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Excluded dates still count towards COUNT.
+	want := []time.Time{
+		time.Date(2025, 9, 3, 8, 0, 0, 0, wita),
+		time.Date(2025, 9, 8, 8, 0, 0, 0, wita),
+		time.Date(2025, 9, 15, 8, 0, 0, 0, wita),
+		time.Date(2025, 9, 17, 8, 0, 0, 0, wita),
+	}
+	assertTimes(t, got, want)
+}
+
+func TestOccurrencesIntervalAndUntil(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;INTERVAL=2;UNTIL=20251006", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2025, 9, 8, 13, 30, 0, 0, wita)
+	got, err := rule.Occurrences(dtstart, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{
+		time.Date(2025, 9, 8, 13, 30, 0, 0, wita),
+		time.Date(2025, 9, 22, 13, 30, 0, 0, wita),
+		time.Date(2025, 10, 6, 13, 30, 0, 0, wita),
+	}
+	assertTimes(t, got, want)
+
+	rule, err = Parse("FREQ=DAILY;INTERVAL=3;COUNT=3", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = rule.Occurrences(dtstart, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []time.Time{
+		time.Date(2025, 9, 8, 13, 30, 0, 0, wita),
+		time.Date(2025, 9, 11, 13, 30, 0, 0, wita),
+		time.Date(2025, 9, 14, 13, 30, 0, 0, wita),
+	}
+	assertTimes(t, got, want)
+}
+
+func TestOccurrencesLimits(t *testing.T) {
+	dtstart := time.Date(2025, 9, 8, 8, 0, 0, 0, wita)
+
+	unbounded, err := Parse("FREQ=DAILY", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unbounded.Occurrences(dtstart, nil); err == nil {
+		t.Error("unbounded rule: want an error")
+	}
+
+	rule, err := Parse("FREQ=DAILY;COUNT=201", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rule.Occurrences(dtstart, nil); !errors.Is(err, ErrTooManyOccurrences) {
+		t.Errorf("got %v, want ErrTooManyOccurrences", err)
+	}
+
+	rule, err = Parse("FREQ=DAILY;COUNT=200", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rule.Occurrences(dtstart, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != MaxOccurrences {
+		t.Errorf("got %d occurrences, want %d", len(got), MaxOccurrences)
+	}
+}
+
+func TestOverlapping(t *testing.T) {
+	rule, err := Parse("FREQ=DAILY", wita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2025, 9, 1, 22, 0, 0, 0, wita)
+	from := time.Date(2025, 9, 3, 0, 0, 0, 0, wita)
+	to := time.Date(2025, 9, 4, 0, 0, 0, 0, wita)
+
+	// Four-hour occurrences: the one starting on the 2nd runs into the 3rd.
+	got := rule.Overlapping(dtstart, 4*time.Hour, from, to)
+	want := []time.Time{
+		time.Date(2025, 9, 2, 22, 0, 0, 0, wita),
+		time.Date(2025, 9, 3, 22, 0, 0, 0, wita),
+	}
+	assertTimes(t, got, want)
+}
+
+func assertTimes(t *testing.T, got, want []time.Time) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d times %v, want %d %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("time %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
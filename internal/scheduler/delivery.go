@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxDeliveryAttempts is how many times a notification is tried before
+	// the scheduler gives up on it.
+	maxDeliveryAttempts = 5
+	// deliveryLease is how long a run owns a delivery it claimed. A run that
+	// crashes mid-delivery leaves the claim behind, and the delivery is
+	// retried once the lease has expired.
+	deliveryLease = 10 * time.Minute
+)
+
+// delivery names the fields that track one notification about a document:
+// when it was delivered, how many attempts were made, and until when the run
+// currently attempting it owns it.
+type delivery struct {
+	sent     string
+	attempts string
+	claimed  string
+}
+
+var (
+	overdueDelivery  = delivery{sent: "overdue_notified_at", attempts: "overdue_notify_attempts", claimed: "overdue_notify_claimed_until"}
+	reminderDelivery = delivery{sent: "reminder_sent_at", attempts: "reminder_attempts", claimed: "reminder_claimed_until"}
+)
+
+// pending adds to filter the conditions under which the notification still
+// has to be sent and nobody else is sending it.
+func (d delivery) pending(filter bson.M, now time.Time) bson.M {
+	filter[d.sent] = nil
+	filter[d.attempts] = bson.M{"$not": bson.M{"$gte": maxDeliveryAttempts}}
+	filter[d.claimed] = bson.M{"$not": bson.M{"$gt": now}}
+	return filter
+}
+
+// deliver sends notification about the document id in collection, unless it
+// was already delivered or another run is delivering it. filter holds the
+// conditions the document must still meet.
+//
+// The attempt is claimed atomically before sending, so overlapping runs and
+// several instances never send at the same time. The delivery is only
+// recorded once the notifier succeeded; a failed attempt releases the claim
+// so the next run retries it. A retry goes through every channel again, so a
+// notification that reached one channel but failed on another may arrive
+// twice on the first.
+func (s *Scheduler) deliver(ctx context.Context, collection *mongo.Collection, d delivery, id primitive.ObjectID, filter bson.M, notification models.Notification, now time.Time) error {
+	filter["_id"] = id
+	lease := now.Add(deliveryLease)
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{d.attempts: 1})
+	claimed, err := collection.FindOneAndUpdate(ctx, d.pending(filter, now), bson.M{
+		"$set": bson.M{d.claimed: lease},
+		"$inc": bson.M{d.attempts: 1},
+	}, opts).Raw()
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("claiming %s notification for %s: %w", notification.Type, id.Hex(), err)
+	}
+
+	notifyErr := s.notifier.Notify(ctx, notification)
+	update := bson.M{"$unset": bson.M{d.claimed: ""}}
+	if notifyErr == nil {
+		update["$set"] = bson.M{d.sent: time.Now()}
+	} else {
+		attempt, _ := claimed.Lookup(d.attempts).AsInt64OK()
+		if attempt >= maxDeliveryAttempts {
+			log.Printf("Giving up on %s notification for %s after %d attempts: %v", notification.Type, id.Hex(), attempt, notifyErr)
+		} else {
+			log.Printf("Failed to deliver %s notification for %s (attempt %d), retrying on the next run: %v", notification.Type, id.Hex(), attempt, notifyErr)
+		}
+	}
+
+	// Only release our own claim: the document may have been reset for a
+	// new notification in the meantime, e.g. by rescheduling a reservation.
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": id, d.claimed: lease}, update); err != nil {
+		return fmt.Errorf("recording %s notification for %s: %w", notification.Type, id.Hex(), err)
+	}
+	return nil
+}
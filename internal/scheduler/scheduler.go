@@ -0,0 +1,165 @@
+// Package scheduler runs periodic background jobs: marking loans that were
+// not returned in time as overdue and reminding users of reservations that
+// are about to start.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/department"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/notify"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Scheduler marks overdue loans and sends reservation reminders.
+//
+// Each notification's delivery is tracked on the document it is about, apart
+// from the change that made it due: a loan becomes Overdue first and its
+// borrower is notified afterwards. Deliveries that fail are retried on later
+// runs, up to maxDeliveryAttempts times.
+type Scheduler struct {
+	db           *mongo.Database
+	notifier     notify.Notifier
+	interval     time.Duration
+	reminderLead time.Duration
+}
+
+// New creates a Scheduler configured by cfg.
+func New(db *mongo.Database, notifier notify.Notifier, cfg config.Config) *Scheduler {
+	return &Scheduler{
+		db:           db,
+		notifier:     notifier,
+		interval:     cfg.SchedulerInterval,
+		reminderLead: cfg.ReservationReminderLead,
+	}
+}
+
+// Run runs the jobs right away and then every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil {
+			log.Printf("Scheduler run failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce runs every job once. A failing job does not stop the others.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	now := time.Now()
+	return errors.Join(
+		s.markOverdueLoans(ctx, now),
+		s.remindReservations(ctx, now),
+	)
+}
+
+// markOverdueLoans marks picked up loans whose period has ended as Overdue
+// and notifies their borrowers.
+func (s *Scheduler) markOverdueLoans(ctx context.Context, now time.Time) error {
+	collection := s.db.Collection("inventory_requests")
+	_, err := collection.UpdateMany(ctx,
+		bson.M{"status": models.InventoryRequestPickedUp, "needed_until": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": models.InventoryRequestOverdue, "overdue_at": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("marking overdue loans: %w", err)
+	}
+
+	// Notify about every overdue loan whose notice is still outstanding,
+	// including those whose delivery failed on an earlier run.
+	filter := func() bson.M {
+		return bson.M{"status": models.InventoryRequestOverdue, "user_id": bson.M{"$ne": nil}}
+	}
+	cursor, err := collection.Find(ctx, overdueDelivery.pending(filter(), now))
+	if err != nil {
+		return fmt.Errorf("finding overdue loans: %w", err)
+	}
+	var loans []models.InventoryRequest
+	if err := cursor.All(ctx, &loans); err != nil {
+		return fmt.Errorf("reading overdue loans: %w", err)
+	}
+
+	for _, loan := range loans {
+		id := loan.ID
+		notification := models.Notification{
+			UserID: *loan.UserID,
+			Type:   models.NotificationLoanOverdue,
+			Title:  "Peminjaman terlambat dikembalikan",
+			Message: fmt.Sprintf("Peminjaman %s (%d × %s) seharusnya dikembalikan pada %s. Segera kembalikan barang ke admin.",
+				loan.RequestID, loan.Quantity, loan.ItemName, loan.NeededUntil.In(department.Location).Format("02 Jan 2006 15:04")),
+			RefID: &id,
+		}
+		if err := s.deliver(ctx, collection, overdueDelivery, id, filter(), notification, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remindReservations notifies the owners of approved reservations starting
+// within the reminder lead time. Reservations whose start was missed while
+// the scheduler was not running are not reminded, and neither are those
+// whose reminder could not be delivered before they started.
+func (s *Scheduler) remindReservations(ctx context.Context, now time.Time) error {
+	collection := s.db.Collection("reservations")
+	filter := func() bson.M {
+		return bson.M{
+			"status":     models.ReservationApproved,
+			"start_time": bson.M{"$gt": now, "$lte": now.Add(s.reminderLead)},
+		}
+	}
+	cursor, err := collection.Find(ctx, reminderDelivery.pending(filter(), now))
+	if err != nil {
+		return fmt.Errorf("finding upcoming reservations: %w", err)
+	}
+	var reservations []models.Reservation
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return fmt.Errorf("reading upcoming reservations: %w", err)
+	}
+
+	roomNames := map[primitive.ObjectID]string{}
+	for _, reservation := range reservations {
+		roomName, ok := roomNames[reservation.RoomID]
+		if !ok {
+			var room models.Room
+			if err := s.db.Collection("rooms").FindOne(ctx, bson.M{"_id": reservation.RoomID}).Decode(&room); err == nil {
+				roomName = room.Name
+			} else {
+				roomName = "ruangan"
+			}
+			roomNames[reservation.RoomID] = roomName
+		}
+
+		id := reservation.ID
+		notification := models.Notification{
+			UserID: reservation.UserID,
+			Type:   models.NotificationReservationReminder,
+			Title:  "Pengingat reservasi",
+			Message: fmt.Sprintf("Reservasi Anda untuk %s dimulai pada %s.",
+				roomName, reservation.StartTime.In(department.Location).Format("02 Jan 2006 15:04")),
+			RefID: &id,
+		}
+		if err := s.deliver(ctx, collection, reminderDelivery, id, filter(), notification, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
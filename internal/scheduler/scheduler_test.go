@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// flakyNotifier fails the first failures deliveries and records the rest.
+type flakyNotifier struct {
+	mu        sync.Mutex
+	failures  int
+	delivered []models.Notification
+}
+
+func (n *flakyNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.failures > 0 {
+		n.failures--
+		return errors.New("mail server unavailable")
+	}
+	n.delivered = append(n.delivered, notification)
+	return nil
+}
+
+func TestFailedDeliveriesAreRetried(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database("jte_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	now := time.Now()
+	userID := primitive.NewObjectID()
+	due := now.Add(-time.Hour)
+	loan := models.InventoryRequest{
+		ID:          primitive.NewObjectID(),
+		RequestID:   "REQ-RETRY",
+		ItemName:    "Proyektor",
+		Quantity:    1,
+		Status:      models.InventoryRequestPickedUp,
+		UserID:      &userID,
+		NeededUntil: &due,
+	}
+	if _, err := db.Collection("inventory_requests").InsertOne(ctx, loan); err != nil {
+		t.Fatal(err)
+	}
+	reservation := models.Reservation{
+		ID:        primitive.NewObjectID(),
+		RoomID:    primitive.NewObjectID(),
+		UserID:    userID,
+		StartTime: now.Add(30 * time.Minute),
+		EndTime:   now.Add(90 * time.Minute),
+		Status:    models.ReservationApproved,
+	}
+	if _, err := db.Collection("reservations").InsertOne(ctx, reservation); err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := &flakyNotifier{failures: 2}
+	s := &Scheduler{db: db, notifier: notifier, interval: time.Minute, reminderLead: time.Hour}
+
+	// Both deliveries fail on the first run and go through on the second;
+	// a third run sends nothing more.
+	for run := 0; run < 3; run++ {
+		if err := s.RunOnce(ctx); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+	if len(notifier.delivered) != 2 {
+		t.Fatalf("got %d notifications, want 2", len(notifier.delivered))
+	}
+
+	var stored models.InventoryRequest
+	if err := db.Collection("inventory_requests").FindOne(ctx, bson.M{"_id": loan.ID}).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.InventoryRequestOverdue || stored.OverdueNotifiedAt == nil || stored.OverdueNotifyAttempts != 2 {
+		t.Errorf("loan: got status %q, notified %v after %d attempts, want Overdue, notified after 2",
+			stored.Status, stored.OverdueNotifiedAt, stored.OverdueNotifyAttempts)
+	}
+	var reminded models.Reservation
+	if err := db.Collection("reservations").FindOne(ctx, bson.M{"_id": reservation.ID}).Decode(&reminded); err != nil {
+		t.Fatal(err)
+	}
+	if reminded.ReminderSentAt == nil || reminded.ReminderAttempts != 2 || reminded.ReminderClaimedUntil != nil {
+		t.Errorf("reservation: got sent %v after %d attempts, claim %v, want sent after 2 and no claim",
+			reminded.ReminderSentAt, reminded.ReminderAttempts, reminded.ReminderClaimedUntil)
+	}
+}
+
+func TestDeliveriesStopAfterMaxAttempts(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database("jte_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	userID := primitive.NewObjectID()
+	due := time.Now().Add(-time.Hour)
+	loan := models.InventoryRequest{ID: primitive.NewObjectID(), Status: models.InventoryRequestPickedUp, UserID: &userID, NeededUntil: &due}
+	if _, err := db.Collection("inventory_requests").InsertOne(ctx, loan); err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := &flakyNotifier{failures: maxDeliveryAttempts + 1}
+	s := &Scheduler{db: db, notifier: notifier, interval: time.Minute, reminderLead: time.Hour}
+	for run := 0; run < maxDeliveryAttempts+1; run++ {
+		if err := s.RunOnce(ctx); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+	if notifier.failures != 1 {
+		t.Errorf("got %d attempts, want %d", maxDeliveryAttempts+1-notifier.failures, maxDeliveryAttempts)
+	}
+}
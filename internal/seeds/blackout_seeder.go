@@ -0,0 +1,51 @@
+package seeds
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SeedBlackouts populates the database with the closures announced in SeedAnnouncements.
+func SeedBlackouts(db *mongo.Database) {
+	collection := db.Collection("blackouts")
+
+	blackouts := []models.Blackout{
+		{
+			Title:     "Libur Hari Raya Idul Fitri",
+			Reason:    "Libur Hari Raya Idul Fitri 8 - 15 April 2025",
+			Scope:     models.BlackoutGlobal,
+			StartTime: parseDateTime("2025-04-08T00:00:00+08:00"),
+			EndTime:   parseDateTime("2025-04-16T00:00:00+08:00"),
+		},
+		{
+			Title:     "Maintenance Sistem",
+			Reason:    "Maintenance sistem 10 Maret 2025 pukul 14:00 - 16:00 WIB",
+			Scope:     models.BlackoutGlobal,
+			StartTime: parseDateTime("2025-03-10T14:00:00+07:00"),
+			EndTime:   parseDateTime("2025-03-10T16:00:00+07:00"),
+		},
+	}
+
+	for _, blackout := range blackouts {
+		// Use title as a unique key for seeding to prevent duplicates
+		var existing models.Blackout
+		err := collection.FindOne(context.TODO(), bson.M{"title": blackout.Title}).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			blackout.ID = primitive.NewObjectID()
+			blackout.CreatedAt = time.Now()
+			_, insertErr := collection.InsertOne(context.TODO(), blackout)
+			if insertErr != nil {
+				log.Printf("Failed to seed blackout '%s': %v", blackout.Title, insertErr)
+			} else {
+				fmt.Printf("Successfully seeded blackout: '%s'\n", blackout.Title)
+			}
+		}
+	}
+}
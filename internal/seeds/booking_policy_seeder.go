@@ -0,0 +1,43 @@
+package seeds
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SeedBookingPolicies creates the default booking policies. Admins are only
+// bound by the department's opening hours; students cannot book the auditorium-class rooms.
+func SeedBookingPolicies(db *mongo.Database) {
+	collection := db.Collection("booking_policies")
+
+	policies := []models.BookingPolicy{
+		{RoomType: "*", Role: "admin", AllowBooking: true},
+		{RoomType: "*", Role: "superadmin", AllowBooking: true},
+		{RoomType: "high", Role: "student", AllowBooking: false},
+		{RoomType: "high", Role: "*", AllowBooking: true, MaxDurationMinutes: 480, MinLeadMinutes: 3 * 24 * 60, MaxAdvanceDays: 90, AllowedFrom: "07:00", AllowedUntil: "21:00", MaxActiveReservations: 2},
+		{RoomType: "medium", Role: "*", AllowBooking: true, MaxDurationMinutes: 240, MinLeadMinutes: 24 * 60, MaxAdvanceDays: 60, AllowedFrom: "07:00", AllowedUntil: "18:00", MaxActiveReservations: 3},
+		{RoomType: "low", Role: "*", AllowBooking: true, MaxDurationMinutes: 180, MinLeadMinutes: 60, MaxAdvanceDays: 30, AllowedFrom: "07:00", AllowedUntil: "18:00", MaxActiveReservations: 3},
+	}
+
+	for _, policy := range policies {
+		var existing models.BookingPolicy
+		err := collection.FindOne(context.TODO(), bson.M{"room_type": policy.RoomType, "role": policy.Role}).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			policy.ID = primitive.NewObjectID()
+			policy.UpdatedAt = time.Now()
+			_, insertErr := collection.InsertOne(context.TODO(), policy)
+			if insertErr != nil {
+				log.Printf("Failed to seed booking policy %s/%s: %v", policy.RoomType, policy.Role, insertErr)
+			} else {
+				fmt.Printf("Successfully seeded booking policy: %s/%s\n", policy.RoomType, policy.Role)
+			}
+		}
+	}
+}
@@ -12,11 +12,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// SeedStatusData populates the database with initial rooms and inventory requests.
+// SeedStatusData populates the database with initial rooms, inventory items,
+// inventory requests and maintenance windows.
 func SeedStatusData(db *mongo.Database) {
 	fmt.Println("Seeding status data...")
 	seedRooms(db)
+	seedInventoryItems(db)
+	seedAssetUnits(db)
 	seedInventoryRequests(db)
+	seedMaintenanceWindows(db)
 	fmt.Println("Status data seeding complete.")
 }
 
@@ -61,6 +65,68 @@ func seedRooms(db *mongo.Database) {
 	}
 }
 
+func seedInventoryItems(db *mongo.Database) {
+	itemsCollection := db.Collection("inventory_items")
+	now := time.Now()
+	items := []models.InventoryItem{
+		{Code: "LPT", Name: "Laptop", Category: "Komputer", TotalQuantity: 5, Location: "Ruang Admin Jurusan Teknik Elektro", Condition: models.ItemConditionGood},
+		{Code: "PRJ", Name: "Proyektor", Category: "Presentasi", TotalQuantity: 4, Location: "Ruang Admin Jurusan Teknik Elektro", Condition: models.ItemConditionGood},
+		{Code: "HDMI", Name: "Kabel HDMI", Category: "Kabel", TotalQuantity: 10, Location: "Ruang Admin Jurusan Teknik Elektro", Condition: models.ItemConditionGood},
+		{Code: "SPD", Name: "Papan Tulis Spidol", Category: "Alat Tulis", TotalQuantity: 3, Location: "Gudang Jurusan Teknik Elektro", Condition: models.ItemConditionFair},
+	}
+
+	for _, item := range items {
+		err := itemsCollection.FindOne(context.TODO(), bson.M{"code": item.Code}).Err()
+		if err == mongo.ErrNoDocuments {
+			item.ID = primitive.NewObjectID()
+			item.CreatedAt = now
+			item.UpdatedAt = now
+			if _, insertErr := itemsCollection.InsertOne(context.TODO(), item); insertErr != nil {
+				log.Printf("Failed to seed inventory item %s: %v", item.Code, insertErr)
+			} else {
+				fmt.Printf("Successfully seeded inventory item: %s\n", item.Name)
+			}
+		} else if err != nil {
+			log.Printf("Error checking for inventory item %s: %v", item.Code, err)
+		}
+	}
+}
+
+// seedAssetUnits tags the laptops and projectors individually.
+func seedAssetUnits(db *mongo.Database) {
+	unitsCollection := db.Collection("asset_units")
+	now := time.Now()
+	for code, count := range map[string]int{"LPT": 5, "PRJ": 4} {
+		var item models.InventoryItem
+		if err := db.Collection("inventory_items").FindOne(context.TODO(), bson.M{"code": code}).Decode(&item); err != nil {
+			log.Printf("Error finding inventory item %s: %v", code, err)
+			continue
+		}
+		for i := 1; i <= count; i++ {
+			tag := fmt.Sprintf("%s-%04d", code, i)
+			err := unitsCollection.FindOne(context.TODO(), bson.M{"asset_tag": tag}).Err()
+			if err == mongo.ErrNoDocuments {
+				unit := models.AssetUnit{
+					ID:        primitive.NewObjectID(),
+					ItemID:    item.ID,
+					AssetTag:  tag,
+					Status:    models.AssetUnitAvailable,
+					Condition: models.ItemConditionGood,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				if _, insertErr := unitsCollection.InsertOne(context.TODO(), unit); insertErr != nil {
+					log.Printf("Failed to seed asset unit %s: %v", tag, insertErr)
+				} else {
+					fmt.Printf("Successfully seeded asset unit: %s\n", tag)
+				}
+			} else if err != nil {
+				log.Printf("Error checking for asset unit %s: %v", tag, err)
+			}
+		}
+	}
+}
+
 func seedInventoryRequests(db *mongo.Database) {
 	inventoryCollection := db.Collection("inventory_requests")
 	requests := []models.InventoryRequest{
@@ -89,3 +155,34 @@ func parseDate(dateStr string) time.Time {
 	t, _ := time.Parse("2006-01-02", dateStr)
 	return t
 }
+
+// seedMaintenanceWindows schedules the maintenance announced for JTE-2.
+func seedMaintenanceWindows(db *mongo.Database) {
+	var room models.Room
+	err := db.Collection("rooms").FindOne(context.TODO(), bson.M{"name": "JTE-2"}).Decode(&room)
+	if err != nil {
+		log.Printf("Skipping maintenance seeding, room JTE-2 not found: %v", err)
+		return
+	}
+
+	collection := db.Collection("maintenance_windows")
+	window := models.MaintenanceWindow{
+		RoomID:    room.ID,
+		Title:     "Perbaikan plafon JTE-2",
+		Notes:     "Kebocoran pada plafon ruangan JTE-2",
+		StartTime: parseDateTime("2025-04-10T08:00:00+08:00"),
+		EndTime:   parseDateTime("2025-04-30T17:00:00+08:00"),
+		CreatedAt: time.Now(),
+	}
+
+	var existing models.MaintenanceWindow
+	err = collection.FindOne(context.TODO(), bson.M{"room_id": room.ID, "title": window.Title}).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		window.ID = primitive.NewObjectID()
+		if _, insertErr := collection.InsertOne(context.TODO(), window); insertErr != nil {
+			log.Printf("Failed to seed maintenance window '%s': %v", window.Title, insertErr)
+		} else {
+			fmt.Printf("Successfully seeded maintenance window: '%s'\n", window.Title)
+		}
+	}
+}
@@ -52,6 +52,8 @@ func seedUser(collection *mongo.Collection, email, password, role string) {
 		Email:    email,
 		Password: string(hashedPassword),
 		Role:     role,
+
+		EmailVerified: true,
 	}
 
 	_, err = collection.InsertOne(context.TODO(), newUser)
@@ -0,0 +1,207 @@
+// Package sessions stores login sessions and rotates their refresh tokens.
+package sessions
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is the collection holding sessions.
+const Collection = "sessions"
+
+var (
+	// ErrInvalidToken is returned for refresh tokens that belong to no live session.
+	ErrInvalidToken = errors.New("invalid or expired refresh token")
+	// ErrTokenReused is returned when an already rotated refresh token is
+	// presented again. The whole session has been revoked by then.
+	ErrTokenReused = errors.New("refresh token reuse detected")
+	// ErrSessionRevoked is returned for sessions that were revoked, expired or never existed.
+	ErrSessionRevoked = errors.New("session revoked or expired")
+)
+
+// Store manages sessions in MongoDB.
+type Store struct {
+	db  *mongo.Database
+	ttl time.Duration
+}
+
+// NewStore creates a session Store. Sessions, and their refresh tokens,
+// expire ttl after they are created.
+func NewStore(db *mongo.Database, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl}
+}
+
+// Create starts a session for the user and returns it with its first refresh
+// token. amr lists how the user authenticated.
+func (s *Store) Create(ctx context.Context, userID primitive.ObjectID, userAgent, ip string, amr []string) (*models.Session, string, error) {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:                  primitive.NewObjectID(),
+		UserID:              userID,
+		CurrentTokenHash:    hash,
+		PreviousTokenHashes: []string{},
+		UserAgent:           userAgent,
+		IP:                  ip,
+		AMR:                 amr,
+		CreatedAt:           now,
+		LastSeenAt:          now,
+		ExpiresAt:           now.Add(s.ttl),
+	}
+	if _, err := s.db.Collection(Collection).InsertOne(ctx, session); err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// Rotate exchanges a refresh token for a new one. Presenting a token that was
+// already rotated revokes the session, since either the client or an
+// attacker holds a stolen copy.
+func (s *Store) Rotate(ctx context.Context, token, userAgent, ip string) (*models.Session, string, error) {
+	hash := auth.HashToken(token)
+	next, nextHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"current_token_hash": hash,
+		"revoked_at":         nil,
+		"expires_at":         bson.M{"$gt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"current_token_hash": nextHash,
+			"user_agent":         userAgent,
+			"ip":                 ip,
+			"last_seen_at":       now,
+		},
+		"$push": bson.M{"previous_token_hashes": hash},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var session models.Session
+	err = s.db.Collection(Collection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&session)
+	if err == nil {
+		return &session, next, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, "", err
+	}
+
+	// The token is not current. If it was once, it is being replayed.
+	result, err := s.db.Collection(Collection).UpdateOne(ctx,
+		bson.M{"previous_token_hashes": hash, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": now, "revoked_reason": models.SessionRevokedReuse}},
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	if result.MatchedCount > 0 {
+		return nil, "", ErrTokenReused
+	}
+	return nil, "", ErrInvalidToken
+}
+
+// FindByToken returns the session a current or rotated refresh token belongs to.
+func (s *Store) FindByToken(ctx context.Context, token string) (*models.Session, error) {
+	hash := auth.HashToken(token)
+	filter := bson.M{"$or": bson.A{
+		bson.M{"current_token_hash": hash},
+		bson.M{"previous_token_hashes": hash},
+	}}
+
+	var session models.Session
+	err := s.db.Collection(Collection).FindOne(ctx, filter).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Revoke ends a session. Revoking an already revoked session is a no-op.
+func (s *Store) Revoke(ctx context.Context, id primitive.ObjectID, reason string) error {
+	_, err := s.db.Collection(Collection).UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}},
+	)
+	return err
+}
+
+// ValidateSession returns ErrSessionRevoked unless the session is still live.
+// It is called for every authenticated request.
+func (s *Store) ValidateSession(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "revoked_at": nil, "expires_at": bson.M{"$gt": time.Now()}}
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	err := s.db.Collection(Collection).FindOne(ctx, filter, opts).Err()
+	if err == mongo.ErrNoDocuments {
+		return ErrSessionRevoked
+	}
+	return err
+}
+
+// ListActive returns the user's live sessions, most recently used first.
+func (s *Store) ListActive(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	filter := bson.M{"user_id": userID, "revoked_at": nil, "expires_at": bson.M{"$gt": time.Now()}}
+	opts := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}})
+
+	cursor, err := s.db.Collection(Collection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.Session
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RevokeOwned revokes one of the user's live sessions. It returns
+// ErrSessionRevoked when the user has no such live session.
+func (s *Store) RevokeOwned(ctx context.Context, userID, id primitive.ObjectID, reason string) error {
+	result, err := s.db.Collection(Collection).UpdateOne(ctx,
+		bson.M{"_id": id, "user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// RevokeAll revokes every live session of the user except the listed ones and
+// returns how many were revoked.
+func (s *Store) RevokeAll(ctx context.Context, userID primitive.ObjectID, reason string, except ...primitive.ObjectID) (int64, error) {
+	filter := bson.M{"user_id": userID, "revoked_at": nil}
+	if len(except) > 0 {
+		filter["_id"] = bson.M{"$nin": except}
+	}
+	result, err := s.db.Collection(Collection).UpdateMany(ctx, filter,
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
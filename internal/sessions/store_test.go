@@ -0,0 +1,90 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRotateRevokesSessionOnReplayedToken(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	store := NewStore(db, time.Hour)
+
+	session, first, err := store.Create(ctx, primitive.NewObjectID(), "test", "127.0.0.1", []string{"pwd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, second, err := store.Rotate(ctx, first, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first token now sits in previous_token_hashes.
+	if _, _, err := store.Rotate(ctx, first, "attacker", "192.0.2.1"); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("replaying a rotated token: got %v, want %v", err, ErrTokenReused)
+	}
+
+	var stored models.Session
+	if err := db.Collection(Collection).FindOne(ctx, bson.M{"_id": session.ID}).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.RevokedAt == nil || stored.RevokedReason != models.SessionRevokedReuse {
+		t.Errorf("session after replay: revoked at %v for %q, want revoked for %q", stored.RevokedAt, stored.RevokedReason, models.SessionRevokedReuse)
+	}
+	if _, _, err := store.Rotate(ctx, second, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("rotating the current token after a replay: got %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestRotateRejectsDeadSessions(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		kill func(store *Store, session *models.Session) error
+	}{
+		{
+			name: "expired",
+			ttl:  -time.Minute,
+			kill: func(*Store, *models.Session) error { return nil },
+		},
+		{
+			name: "revoked",
+			ttl:  time.Hour,
+			kill: func(store *Store, session *models.Session) error {
+				return store.Revoke(ctx, session.ID, models.SessionRevokedByUser)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(db, tt.ttl)
+			session, token, err := store.Create(ctx, primitive.NewObjectID(), "test", "127.0.0.1", []string{"pwd"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.kill(store, session); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := store.Rotate(ctx, token, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("got %v, want %v", err, ErrInvalidToken)
+			}
+			var stored models.Session
+			if err := db.Collection(Collection).FindOne(ctx, bson.M{"_id": session.ID}).Decode(&stored); err != nil {
+				t.Fatal(err)
+			}
+			if stored.CurrentTokenHash != session.CurrentTokenHash {
+				t.Error("the refresh token of a dead session was rotated")
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package throttle slows down repeated failed logins. Failures are counted
+// per key (an account or a client IP); after a few free attempts every
+// further failure doubles the wait before the next attempt, and enough
+// failures lock the key out for a while.
+package throttle
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is the collection holding failure counters.
+const Collection = "login_throttles"
+
+// Limits configures how quickly a key is slowed down.
+type Limits struct {
+	FreeAttempts    int           // failures allowed without any delay
+	BaseDelay       time.Duration // delay after the first failure past FreeAttempts
+	MaxDelay        time.Duration // cap for the exponential delay
+	LockoutAfter    int           // failures that trigger a lockout; 0 disables it
+	LockoutDuration time.Duration
+	Window          time.Duration // failures older than this are forgotten
+}
+
+// Default limits. A single account is locked quickly; an IP, which may be
+// shared by a whole campus network, gets more room.
+var (
+	AccountLimits = Limits{
+		FreeAttempts:    3,
+		BaseDelay:       2 * time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+	IPLimits = Limits{
+		FreeAttempts:    10,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAfter:    50,
+		LockoutDuration: 30 * time.Minute,
+		Window:          time.Hour,
+	}
+	// MailLimits allows one mailed link per address per minute.
+	MailLimits = Limits{
+		BaseDelay: time.Minute,
+		MaxDelay:  time.Minute,
+		Window:    time.Hour,
+	}
+)
+
+// AccountKey is the key for failures against one account.
+func AccountKey(email string) string {
+	return "account:" + email
+}
+
+// IPKey is the key for failures from one client address.
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// MailKey is the key for links mailed to one address, such as password
+// resets.
+func MailKey(email string) string {
+	return "mail:" + email
+}
+
+// Store keeps failure counters in MongoDB.
+type Store struct {
+	db *mongo.Database
+}
+
+// NewStore creates a throttle Store.
+func NewStore(db *mongo.Database) *Store {
+	return &Store{db: db}
+}
+
+type counter struct {
+	Failures     int                `bson:"failures"`
+	BlockedUntil *time.Time         `bson:"blocked_until"`
+	AttemptID    primitive.ObjectID `bson:"attempt_id"`
+}
+
+// Reserve counts an attempt against the key before the credentials are
+// checked, so that parallel guesses cannot all slip in before any of them is
+// recorded. It returns how long the caller must wait when the attempt is
+// refused, or zero when it may go ahead. An attempt that turns out to be
+// successful is taken back with Release or Reset.
+//
+// A blocked key is not counted further. Otherwise the counter is incremented
+// atomically and every attempt gets its own count, so no more than
+// LockoutAfter attempts are ever let through, however many run at once.
+func (s *Store) Reserve(ctx context.Context, key string, limits Limits) (time.Duration, error) {
+	now := time.Now()
+	collection := s.db.Collection(Collection)
+	attemptID := primitive.NewObjectID()
+
+	// Restart the count when the previous failures fell out of the window.
+	blocked := bson.M{"$gt": bson.A{"$blocked_until", now}}
+	keep := func(field string, value interface{}) bson.M {
+		return bson.M{"$cond": bson.A{blocked, "$" + field, value}}
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failures": keep("failures", bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$expires_at", now}},
+			bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+			1,
+		}}),
+		"attempt_id":      keep("attempt_id", attemptID),
+		"last_attempt_at": keep("last_attempt_at", now),
+		"expires_at":      keep("expires_at", now.Add(limits.Window)),
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var c counter
+	if err := collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&c); err != nil {
+		return 0, err
+	}
+	if c.AttemptID != attemptID {
+		return c.BlockedUntil.Sub(now), nil
+	}
+
+	// The block applies to the attempts after this one. Parallel attempts
+	// may set it in any order, so it is only ever extended.
+	delay := limits.delay(c.Failures)
+	if delay > 0 {
+		blockedUntil := now.Add(delay)
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$max": bson.M{
+			"blocked_until": blockedUntil,
+			"expires_at":    blockedUntil, // keep the counter at least as long as the block
+		}})
+		if err != nil {
+			return 0, err
+		}
+	}
+	if limits.LockoutAfter > 0 && c.Failures > limits.LockoutAfter {
+		// Attempts running in parallel took the count past the lockout.
+		return limits.LockoutDuration, nil
+	}
+	return 0, nil
+}
+
+// Release takes back an attempt reserved with Reserve that did not fail, such
+// as a login with the right password from a shared address. A block the
+// attempt caused is left to expire.
+func (s *Store) Release(ctx context.Context, key string) error {
+	_, err := s.db.Collection(Collection).UpdateOne(ctx,
+		bson.M{"_id": key, "failures": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"failures": -1}},
+	)
+	return err
+}
+
+// Reset forgets the failures of a key, after a successful login or when an
+// admin unlocks an account.
+func (s *Store) Reset(ctx context.Context, key string) error {
+	_, err := s.db.Collection(Collection).DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// delay returns how long a key is blocked after the given number of failures.
+func (l Limits) delay(failures int) time.Duration {
+	if l.LockoutAfter > 0 && failures >= l.LockoutAfter {
+		return l.LockoutDuration
+	}
+	excess := failures - l.FreeAttempts
+	if excess <= 0 {
+		return 0
+	}
+	delay := l.BaseDelay
+	for i := 1; i < excess && delay < l.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > l.MaxDelay {
+		delay = l.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,102 @@
+package throttle
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestDelay(t *testing.T) {
+	limits := Limits{
+		FreeAttempts:    3,
+		BaseDelay:       2 * time.Second,
+		MaxDelay:        10 * time.Second,
+		LockoutAfter:    8,
+		LockoutDuration: 15 * time.Minute,
+	}
+	want := []time.Duration{
+		0, 0, 0, 0, // up to FreeAttempts failures are free
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second, // capped at MaxDelay
+		15 * time.Minute, // LockoutAfter reached
+		15 * time.Minute,
+	}
+	for failures, d := range want {
+		if got := limits.delay(failures); got != d {
+			t.Errorf("delay(%d) = %v, want %v", failures, got, d)
+		}
+	}
+
+	limits.LockoutAfter = 0
+	if got := limits.delay(100); got != limits.MaxDelay {
+		t.Errorf("without lockout delay(100) = %v, want %v", got, limits.MaxDelay)
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	for name, limits := range map[string]Limits{"account": AccountLimits, "ip": IPLimits} {
+		if limits.delay(limits.FreeAttempts) != 0 || limits.delay(limits.FreeAttempts+1) != limits.BaseDelay {
+			t.Errorf("%s: the first delay should follow the free attempts", name)
+		}
+		if limits.delay(limits.LockoutAfter) != limits.LockoutDuration {
+			t.Errorf("%s: no lockout after %d failures", name, limits.LockoutAfter)
+		}
+	}
+}
+
+func TestReserveLetsNoMoreThanLockoutAfterThrough(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database("jte_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	// No backoff, so only the lockout limits the parallel attempts.
+	limits := Limits{FreeAttempts: 100, LockoutAfter: 5, LockoutDuration: time.Minute, Window: time.Hour}
+	store := NewStore(db)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	admitted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := store.Reserve(ctx, AccountKey("guess@unsrat.ac.id"), limits)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if wait == 0 {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if admitted != limits.LockoutAfter {
+		t.Errorf("%d attempts admitted, want %d", admitted, limits.LockoutAfter)
+	}
+	if wait, err := store.Reserve(ctx, AccountKey("guess@unsrat.ac.id"), limits); err != nil || wait <= 0 {
+		t.Errorf("after the lockout Reserve = %v, %v; want a wait", wait, err)
+	}
+}
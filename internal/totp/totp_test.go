@@ -0,0 +1,92 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 appendix B, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8 digit codes; a 6 digit code is the last 6 of them.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != v.code {
+			t.Errorf("Code at %d: got %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestCodeAcceptsLowercaseSecret(t *testing.T) {
+	got, err := Code(strings.ToLower(rfcSecret), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("got %s, want 287082", got)
+	}
+}
+
+func TestCodeRejectsInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("got no error for an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	for _, tc := range []struct {
+		name  string
+		step  int64
+		valid bool
+	}{
+		{"current step", current, true},
+		{"one step behind", current - 1, true},
+		{"one step ahead", current + 1, true},
+		{"two steps behind", current - 2, false},
+		{"two steps ahead", current + 2, false},
+	} {
+		code, err := Code(rfcSecret, tc.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, valid := Validate(rfcSecret, code, now)
+		if valid != tc.valid {
+			t.Errorf("%s: got valid %v, want %v", tc.name, valid, tc.valid)
+		}
+		if valid && step != tc.step {
+			t.Errorf("%s: got step %d, want %d", tc.name, step, tc.step)
+		}
+	}
+}
+
+func TestValidateNormalizesInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	if _, valid := Validate(rfcSecret, " 287 082 ", now); !valid {
+		t.Error("code with spaces was rejected")
+	}
+	for _, code := range []string{"", "28708", "2870820", "94287082"} {
+		if _, valid := Validate(rfcSecret, code, now); valid {
+			t.Errorf("%q: got valid, want rejected", code)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package users checks the state of user accounts on every authenticated
+// request.
+package users
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is the collection holding user accounts.
+const Collection = "users"
+
+var (
+	// ErrAccountDisabled is returned for disabled and deleted accounts.
+	ErrAccountDisabled = errors.New("account disabled")
+	// ErrAccountNotFound is returned when no account has the ID.
+	ErrAccountNotFound = errors.New("account not found")
+)
+
+// Store reads user accounts from MongoDB.
+type Store struct {
+	db *mongo.Database
+}
+
+// NewStore creates a user Store.
+func NewStore(db *mongo.Database) *Store {
+	return &Store{db: db}
+}
+
+// ValidateAccount returns an error unless the account exists and is enabled.
+func (s *Store) ValidateAccount(ctx context.Context, id primitive.ObjectID) error {
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"disabled": 1})
+	err := s.db.Collection(Collection).FindOne(ctx, bson.M{"_id": id}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if user.Disabled {
+		return ErrAccountDisabled
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package usertokens issues and redeems single-use tokens that are mailed to
+// users, such as password reset tokens.
+package usertokens
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is the collection holding user tokens.
+const Collection = "user_tokens"
+
+// ErrInvalidToken is returned for tokens that are unknown, expired, already
+// used or issued for another purpose.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Store manages user tokens in MongoDB.
+type Store struct {
+	db *mongo.Database
+}
+
+// NewStore creates a user token Store.
+func NewStore(db *mongo.Database) *Store {
+	return &Store{db: db}
+}
+
+// Issue creates a token for the user and purpose, valid for ttl. Earlier
+// unused tokens of the same purpose stop working, so only the newest link
+// in the user's inbox is valid.
+func (s *Store) Issue(ctx context.Context, userID primitive.ObjectID, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	collection := s.db.Collection(Collection)
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "purpose": purpose, "used_at": nil},
+		bson.M{"$set": bson.M{"expires_at": now}},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = collection.InsertOne(ctx, models.UserToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Consume redeems a token for the given purpose. The token is marked used in
+// the same update that finds it, so it can only be redeemed once.
+func (s *Store) Consume(ctx context.Context, token, purpose string) (*models.UserToken, error) {
+	now := time.Now()
+	filter := bson.M{
+		"token_hash": auth.HashToken(token),
+		"purpose":    purpose,
+		"used_at":    nil,
+		"expires_at": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var userToken models.UserToken
+	err := s.db.Collection(Collection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&userToken)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &userToken, nil
+}
@@ -0,0 +1,68 @@
+// Command jwt-keygen writes a new PEM private key for signing access tokens
+// and prints its key ID.
+//
+// Usage:
+//
+//	go run ./scripts/jwt-keygen -alg EdDSA -out keys/jwt-signing.pem
+//
+// To rotate keys, generate a new key, point JWT_SIGNING_KEY_FILE at it and
+// add the old file to JWT_VERIFICATION_KEY_FILES until the tokens it signed
+// have expired.
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/mariopaath23/backend-jte-ticketing/internal/auth"
+)
+
+func main() {
+	alg := flag.String("alg", "EdDSA", "signing algorithm: EdDSA or RS256")
+	out := flag.String("out", "keys/jwt-signing.pem", "file to write the private key to")
+	flag.Parse()
+
+	var key crypto.Signer
+	var err error
+	switch *alg {
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		log.Fatalf("Unknown algorithm %q, use EdDSA or RS256", *alg)
+	}
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		log.Fatalf("Failed to encode key: %v", err)
+	}
+
+	if _, err := os.Stat(*out); err == nil {
+		log.Fatalf("%s already exists; refusing to overwrite a key", *out)
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0o700); err != nil {
+		log.Fatalf("Failed to create key directory: %v", err)
+	}
+	if err := os.WriteFile(*out, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		log.Fatalf("Failed to write key: %v", err)
+	}
+
+	ks, err := auth.LoadKeySet(*out, nil)
+	if err != nil {
+		log.Fatalf("Failed to load the new key: %v", err)
+	}
+	fmt.Printf("Wrote %s key to %s (kid %s)\n", *alg, *out, ks.JWKS().Keys[0].Kid)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,9 @@ import (
 	"github.com/mariopaath23/backend-jte-ticketing/internal/config"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/database"
 	"github.com/mariopaath23/backend-jte-ticketing/internal/seeds"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/sessions"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/throttle"
+	"github.com/mariopaath23/backend-jte-ticketing/internal/usertokens"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -41,14 +45,26 @@ func main() {
 		migrateLoginLogsCollection(db)
 		migrateRoomsCollection(db)
 		migrateInventoryRequestsCollection(db)
+		migrateInventoryItemsCollection(db)
+		migrateAssetUnitsCollection(db)
 		migrateAnnouncementsCollection(db)
 		migrateReservationsCollection(db)
+		migrateLocksCollection(db)
+		migrateBookingPoliciesCollection(db)
+		migrateBlackoutsCollection(db)
+		migrateMaintenanceWindowsCollection(db)
+		migrateNotificationsCollection(db)
+		migrateSessionsCollection(db)
+		migrateUserTokensCollection(db)
+		migrateLoginThrottlesCollection(db)
 		fmt.Println("Migrations completed successfully.")
 	case "seed":
 		fmt.Println("Running seeders...")
 		seeds.SeedUsers(db)
 		seeds.SeedStatusData(db)
 		seeds.SeedAnnouncements(db)
+		seeds.SeedBookingPolicies(db)
+		seeds.SeedBlackouts(db)
 		fmt.Println("Seeding completed successfully.")
 	default:
 		log.Fatalf("Unknown command: %s. Available commands: 'migrate', 'seed'", command)
@@ -73,6 +89,27 @@ func migrateUsersCollection(db *mongo.Database) {
 	}
 
 	fmt.Println("Successfully created unique index on 'email' field in 'users' collection.")
+
+	// NIM and NIP are unique, but most accounts start without one.
+	identityIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "identity_number", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"identity_number": bson.M{"$type": "string"}}),
+	}
+	if _, err := usersCollection.Indexes().CreateOne(context.TODO(), identityIndex); err != nil {
+		log.Fatalf("Failed to create index on 'identity_number': %v", err)
+	}
+	fmt.Println("Successfully created unique index on 'identity_number' field in 'users' collection.")
+
+	// Accounts created before email verification existed are trusted.
+	result, err := usersCollection.UpdateMany(context.TODO(),
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	if err != nil {
+		log.Fatalf("Failed to mark existing users as verified: %v", err)
+	}
+	fmt.Printf("Marked %d existing user(s) as email verified.\n", result.ModifiedCount)
 }
 
 func migrateLoginLogsCollection(db *mongo.Database) {
@@ -89,20 +126,49 @@ func migrateLoginLogsCollection(db *mongo.Database) {
 		log.Fatalf("Failed to create index on 'login_logs': %v", err)
 	}
 	fmt.Println("Successfully created index on 'user_id' and 'timestamp' fields in 'login_logs' collection.")
+
+	// Failed attempts are logged too; entries from before that were all successful.
+	result, err := loginLogsCollection.UpdateMany(context.TODO(),
+		bson.M{"success": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"success": true}},
+	)
+	if err != nil {
+		log.Fatalf("Failed to backfill 'success' in 'login_logs': %v", err)
+	}
+	fmt.Printf("Marked %d existing login log(s) as successful.\n", result.ModifiedCount)
 }
 
 // migrateRoomsCollection creates indexes for the rooms collection.
 func migrateRoomsCollection(db *mongo.Database) {
-	roomsCollection := db.Collection("rooms")
+	createActiveUniqueIndex(db.Collection("rooms"), "room_id")
+}
+
+// createActiveUniqueIndex makes field unique among documents that are not
+// soft-deleted, so the value of a deleted document can be reused. Documents
+// that are not deleted have no deleted_at, which a partial index cannot
+// select, so the index covers (field, deleted_at) instead: every live
+// document indexes deleted_at as null and collides with another live one,
+// while deleted documents differ by their deletion time. The plain unique
+// index on field created by earlier migrations is dropped.
+func createActiveUniqueIndex(collection *mongo.Collection, field string) {
+	old := field + "_1"
+	if _, err := collection.Indexes().DropOne(context.TODO(), old); err == nil {
+		fmt.Printf("Dropped unique index '%s' in '%s' collection.\n", old, collection.Name())
+	} else {
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || (cmdErr.Name != "IndexNotFound" && cmdErr.Name != "NamespaceNotFound") {
+			log.Fatalf("Failed to drop index '%s' in '%s': %v", old, collection.Name(), err)
+		}
+	}
+
 	indexModel := mongo.IndexModel{
-		Keys:    bson.D{{Key: "room_id", Value: 1}},
+		Keys:    bson.D{{Key: field, Value: 1}, {Key: "deleted_at", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	}
-	_, err := roomsCollection.Indexes().CreateOne(context.TODO(), indexModel)
-	if err != nil {
-		log.Fatalf("Failed to create index on 'room_id': %v", err)
+	if _, err := collection.Indexes().CreateOne(context.TODO(), indexModel); err != nil {
+		log.Fatalf("Failed to create index on '%s': %v", field, err)
 	}
-	fmt.Println("Successfully created unique index on 'room_id' field in 'rooms' collection.")
+	fmt.Printf("Successfully created unique index on '%s' field of documents that are not deleted in '%s' collection.\n", field, collection.Name())
 }
 
 // migrateInventoryRequestsCollection creates indexes for the inventory_requests collection.
@@ -117,6 +183,73 @@ func migrateInventoryRequestsCollection(db *mongo.Database) {
 		log.Fatalf("Failed to create index on 'request_id': %v", err)
 	}
 	fmt.Println("Successfully created unique index on 'request_id' field in 'inventory_requests' collection.")
+
+	// Supports looking up the loans holding units of an item.
+	itemIndex := mongo.IndexModel{Keys: bson.D{{Key: "item_id", Value: 1}, {Key: "status", Value: 1}}}
+	if _, err := inventoryRequestsCollection.Indexes().CreateOne(context.TODO(), itemIndex); err != nil {
+		log.Fatalf("Failed to create index on 'item_id': %v", err)
+	}
+	fmt.Println("Successfully created index on 'item_id' and 'status' fields in 'inventory_requests' collection.")
+
+	// Supports listing a user's own loans, newest first.
+	userIndex := mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "request_date", Value: -1}}}
+	if _, err := inventoryRequestsCollection.Indexes().CreateOne(context.TODO(), userIndex); err != nil {
+		log.Fatalf("Failed to create index on 'user_id': %v", err)
+	}
+	fmt.Println("Successfully created index on 'user_id' and 'request_date' fields in 'inventory_requests' collection.")
+
+	// Supports the scheduler's search for loans past their return date.
+	dueIndex := mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "needed_until", Value: 1}}}
+	if _, err := inventoryRequestsCollection.Indexes().CreateOne(context.TODO(), dueIndex); err != nil {
+		log.Fatalf("Failed to create index on 'needed_until': %v", err)
+	}
+	fmt.Println("Successfully created index on 'status' and 'needed_until' fields in 'inventory_requests' collection.")
+
+	// Loans marked overdue before deliveries were tracked separately were
+	// notified in the same step, so they must not be notified again.
+	result, err := inventoryRequestsCollection.UpdateMany(context.TODO(),
+		bson.M{"overdue_at": bson.M{"$exists": true}, "overdue_notified_at": bson.M{"$exists": false}},
+		[]bson.M{{"$set": bson.M{"overdue_notified_at": "$overdue_at"}}},
+	)
+	if err != nil {
+		log.Fatalf("Failed to backfill 'overdue_notified_at' in 'inventory_requests': %v", err)
+	}
+	fmt.Printf("Marked %d existing overdue loan(s) as notified.\n", result.ModifiedCount)
+}
+
+// migrateInventoryItemsCollection creates indexes for the inventory_items collection.
+func migrateInventoryItemsCollection(db *mongo.Database) {
+	createActiveUniqueIndex(db.Collection("inventory_items"), "code")
+}
+
+// migrateAssetUnitsCollection creates indexes for the asset_units and
+// asset_unit_events collections.
+func migrateAssetUnitsCollection(db *mongo.Database) {
+	collection := db.Collection("asset_units")
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "asset_tag", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Units without a serial number are not indexed, so they do not
+			// collide with each other.
+			Keys: bson.D{{Key: "serial_number", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"serial_number": bson.M{"$type": "string"}}),
+		},
+		{Keys: bson.D{{Key: "item_id", Value: 1}, {Key: "status", Value: 1}}},
+	}
+	if _, err := collection.Indexes().CreateMany(context.TODO(), indexes); err != nil {
+		log.Fatalf("Failed to create indexes on 'asset_units' collection: %v", err)
+	}
+	fmt.Println("Successfully created indexes on 'asset_units' collection.")
+
+	historyIndex := mongo.IndexModel{Keys: bson.D{{Key: "unit_id", Value: 1}, {Key: "created_at", Value: -1}}}
+	if _, err := db.Collection("asset_unit_events").Indexes().CreateOne(context.TODO(), historyIndex); err != nil {
+		log.Fatalf("Failed to create index on 'asset_unit_events' collection: %v", err)
+	}
+	fmt.Println("Successfully created index on 'unit_id' and 'created_at' fields in 'asset_unit_events' collection.")
 }
 
 func migrateAnnouncementsCollection(db *mongo.Database) {
@@ -148,4 +281,177 @@ func migrateReservationsCollection(db *mongo.Database) {
 		log.Fatalf("Failed to create index on 'reservations' collection: %v", err)
 	}
 	fmt.Println("Successfully created index on 'reservations' collection.")
+
+	// Index on series_id for loading and updating the occurrences of a recurring reservation.
+	seriesIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "series_id", Value: 1}, {Key: "start_time", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	}
+	_, err = collection.Indexes().CreateOne(context.TODO(), seriesIndexModel)
+	if err != nil {
+		log.Fatalf("Failed to create index on 'series_id': %v", err)
+	}
+	fmt.Println("Successfully created index on 'series_id' field in 'reservations' collection.")
+
+	// Index on status and start_time for the scheduler's reminder search.
+	reminderIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "start_time", Value: 1}},
+	}
+	_, err = collection.Indexes().CreateOne(context.TODO(), reminderIndexModel)
+	if err != nil {
+		log.Fatalf("Failed to create index on 'status' and 'start_time': %v", err)
+	}
+	fmt.Println("Successfully created index on 'status' and 'start_time' fields in 'reservations' collection.")
+}
+
+// migrateLocksCollection adds a TTL index so expired lock leases are cleaned up.
+func migrateLocksCollection(db *mongo.Database) {
+	collection := db.Collection(database.LocksCollection)
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), indexModel)
+	if err != nil {
+		log.Fatalf("Failed to create TTL index on 'locks' collection: %v", err)
+	}
+	fmt.Println("Successfully created TTL index on 'expires_at' field in 'locks' collection.")
+}
+
+// migrateBookingPoliciesCollection allows at most one policy per room type and role.
+func migrateBookingPoliciesCollection(db *mongo.Database) {
+	collection := db.Collection("booking_policies")
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "room_type", Value: 1}, {Key: "role", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), indexModel)
+	if err != nil {
+		log.Fatalf("Failed to create index on 'booking_policies' collection: %v", err)
+	}
+	fmt.Println("Successfully created unique index on 'room_type' and 'role' fields in 'booking_policies' collection.")
+}
+
+// migrateBlackoutsCollection indexes blackouts by scope and start time for conflict checks.
+func migrateBlackoutsCollection(db *mongo.Database) {
+	collection := db.Collection("blackouts")
+
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "scope", Value: 1}, {Key: "start_time", Value: 1}},
+	}
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), indexModel)
+	if err != nil {
+		log.Fatalf("Failed to create index on 'blackouts' collection: %v", err)
+	}
+	fmt.Println("Successfully created index on 'scope' and 'start_time' fields in 'blackouts' collection.")
+}
+
+// migrateMaintenanceWindowsCollection indexes maintenance windows by room and time range.
+func migrateMaintenanceWindowsCollection(db *mongo.Database) {
+	collection := db.Collection("maintenance_windows")
+
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "room_id", Value: 1},
+			{Key: "start_time", Value: 1},
+			{Key: "end_time", Value: 1},
+		},
+	}
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), indexModel)
+	if err != nil {
+		log.Fatalf("Failed to create index on 'maintenance_windows' collection: %v", err)
+	}
+	fmt.Println("Successfully created index on 'maintenance_windows' collection.")
+}
+
+// migrateNotificationsCollection indexes notifications for listing a user's newest first.
+func migrateNotificationsCollection(db *mongo.Database) {
+	collection := db.Collection("notifications")
+
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	}
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), indexModel)
+	if err != nil {
+		log.Fatalf("Failed to create index on 'notifications' collection: %v", err)
+	}
+	fmt.Println("Successfully created index on 'user_id' and 'created_at' fields in 'notifications' collection.")
+}
+
+// migrateSessionsCollection indexes sessions by refresh token hash and user,
+// and removes them once they expire.
+func migrateSessionsCollection(db *mongo.Database) {
+	collection := db.Collection(sessions.Collection)
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "current_token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "previous_token_hashes", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(context.TODO(), indexModels)
+	if err != nil {
+		log.Fatalf("Failed to create indexes on 'sessions' collection: %v", err)
+	}
+	fmt.Println("Successfully created indexes on 'sessions' collection.")
+}
+
+// migrateUserTokensCollection indexes mailed tokens by hash and removes them
+// once they expire.
+func migrateUserTokensCollection(db *mongo.Database) {
+	collection := db.Collection(usertokens.Collection)
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(context.TODO(), indexModels)
+	if err != nil {
+		log.Fatalf("Failed to create indexes on 'user_tokens' collection: %v", err)
+	}
+	fmt.Println("Successfully created indexes on 'user_tokens' collection.")
+}
+
+// migrateLoginThrottlesCollection removes failed login counters once they expire.
+func migrateLoginThrottlesCollection(db *mongo.Database) {
+	collection := db.Collection(throttle.Collection)
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), indexModel)
+	if err != nil {
+		log.Fatalf("Failed to create TTL index on 'login_throttles' collection: %v", err)
+	}
+	fmt.Println("Successfully created TTL index on 'expires_at' field in 'login_throttles' collection.")
 }